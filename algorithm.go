@@ -0,0 +1,101 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+)
+
+// Relative algorithm strength used by CompareAlgStrength. "none" is
+// weakest, followed by HMAC, followed by the asymmetric algorithms
+// which share a tier per digest size.
+var algStrength = map[Algorithm]int{
+	ALG_NONE:  0,
+	ALG_HS256: 1,
+	ALG_HS384: 2,
+	ALG_HS512: 3,
+	ALG_RS256: 4,
+	ALG_PS256: 4,
+	ALG_ES256: 4,
+	ALG_EDDSA: 4,
+	ALG_RS384: 5,
+	ALG_PS384: 5,
+	ALG_ES384: 5,
+	ALG_RS512: 6,
+	ALG_PS512: 6,
+	ALG_ES512: 6,
+}
+
+func algRank(alg Algorithm) int {
+	rank, ok := algStrength[alg]
+	if !ok {
+		// unknown algorithms rank below "none" so moving to one is
+		// always treated as a downgrade
+		return -1
+	}
+	return rank
+}
+
+// Order two algorithms by strength. Returns -1 if a is weaker than b,
+// 0 if they are equivalent and +1 if a is stronger than b.
+func CompareAlgStrength(a, b Algorithm) int {
+	ra, rb := algRank(a), algRank(b)
+	switch {
+	case ra < rb:
+		return -1
+	case ra > rb:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Guess the algorithm for a token without "alg" from the verification
+// key: RS256 for RSA, ES256/384/512 by curve for ECDSA, EdDSA for
+// Ed25519 and HS256 for symmetric keys. Only used with
+// VerifyOptions.InferAlgFromKey
+func inferAlgFromKey(key crypto.PublicKey) (Algorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return ALG_RS256, nil
+	case *ecdsa.PrivateKey:
+		return inferAlgFromKey(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		for alg, curve := range ecdsaCurves {
+			if k.Curve == curve {
+				return alg, nil
+			}
+		}
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return ALG_EDDSA, nil
+	case []byte:
+		return ALG_HS256, nil
+	}
+	return "", fmt.Errorf("Cannot infer JWS algorithm from key of type %T", key)
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"testing"
+)
+
+func TestCompareAlgStrength(t *testing.T) {
+	tests := []struct {
+		a, b     Algorithm
+		expected int
+	}{
+		{ALG_NONE, ALG_HS256, -1},
+		{ALG_HS256, ALG_NONE, 1},
+		{ALG_HS256, ALG_HS512, -1},
+		{ALG_HS512, ALG_RS256, -1},
+		{ALG_ES256, ALG_HS256, 1},
+		{ALG_ES256, ALG_NONE, 1},
+		{ALG_RS256, ALG_ES256, 0},
+		{ALG_PS384, ALG_ES384, 0},
+		{ALG_ES256, ALG_ES512, -1},
+		{ALG_RS512, ALG_PS256, 1},
+		{ALG_ES256, ALG_ES256, 0},
+		{Algorithm("XS999"), ALG_NONE, -1},
+		{ALG_NONE, Algorithm("XS999"), 1},
+		{Algorithm("XS999"), Algorithm("XS999"), 0},
+	}
+
+	for _, tc := range tests {
+		if got := CompareAlgStrength(tc.a, tc.b); got != tc.expected {
+			t.Errorf("CompareAlgStrength(%s, %s) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Returned for a token whose "jti" matches an earlier token in a batch
+var ErrDuplicateJTI = errors.New("Duplicate jti in batch")
+
+// Settings for VerifyBatch
+type BatchOptions struct {
+	VerifyOptions
+
+	// Reject tokens whose "jti" matches that of an earlier verified
+	// token in the same batch. Only tokens that verify are tracked so
+	// a forgery can't cause a genuine token to be rejected
+	RejectDuplicateJTI bool
+}
+
+// Outcome for a single token in a batch. Err is nil when the token
+// verified
+type BatchResult struct {
+	VerifyResult
+	Err error
+}
+
+// Verify a batch of tokens, returning one result per token in order
+func VerifyBatch(tokens []string, kp KeyProvider, opts BatchOptions) []BatchResult {
+	results := make([]BatchResult, len(tokens))
+	seen := make(map[string]int)
+
+	for ii, jws := range tokens {
+		result, err := VerifyWithOptions(jws, kp, opts.VerifyOptions)
+		results[ii] = BatchResult{VerifyResult: result, Err: err}
+		if err != nil || !opts.RejectDuplicateJTI {
+			continue
+		}
+
+		var claims struct {
+			Jti string `json:"jti"`
+		}
+		if err := json.Unmarshal(result.Payload, &claims); err != nil {
+			results[ii].Err = fmt.Errorf("Failed to decode claims: %v", err)
+			continue
+		}
+		if claims.Jti == "" {
+			continue
+		}
+
+		if first, ok := seen[claims.Jti]; ok {
+			results[ii].Err = fmt.Errorf("%w: %q first seen at index %d", ErrDuplicateJTI, claims.Jti, first)
+			continue
+		}
+		seen[claims.Jti] = ii
+	}
+
+	return results
+}
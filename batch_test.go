@@ -0,0 +1,75 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyBatch_DuplicateJTI(t *testing.T) {
+	tokens := []string{
+		signHS256(`{"alg":"HS256"}`, `{"jti":"a"}`, testHMACKey),
+		signHS256(`{"alg":"HS256"}`, `{"jti":"b"}`, testHMACKey),
+		signHS256(`{"alg":"HS256"}`, `{"jti":"a"}`, testHMACKey),
+		signHS256(`{"alg":"HS256"}`, `{"iss":"no-jti"}`, testHMACKey),
+		signHS256(`{"alg":"HS256"}`, `{"iss":"no-jti"}`, testHMACKey),
+		signHS256(`{"alg":"HS256"}`, `{"jti":"c"}`, []byte("forged")),
+		signHS256(`{"alg":"HS256"}`, `{"jti":"c"}`, testHMACKey),
+	}
+
+	results := VerifyBatch(tokens, ProviderFromKey(testHMACKey), BatchOptions{RejectDuplicateJTI: true})
+	if len(results) != len(tokens) {
+		t.Fatalf("Expected %d results, got %d", len(tokens), len(results))
+	}
+
+	for ii, result := range results {
+		switch ii {
+		case 2:
+			if !errors.Is(result.Err, ErrDuplicateJTI) {
+				t.Errorf("Token %d: expected ErrDuplicateJTI, got %v", ii, result.Err)
+			}
+		case 5:
+			if result.Err == nil || errors.Is(result.Err, ErrDuplicateJTI) {
+				t.Errorf("Token %d: expected verification failure, got %v", ii, result.Err)
+			}
+		default:
+			if result.Err != nil {
+				t.Errorf("Token %d: %v", ii, result.Err)
+			}
+		}
+	}
+}
+
+func TestVerifyBatch_DuplicatesAllowed(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256"}`, `{"jti":"a"}`, testHMACKey)
+
+	results := VerifyBatch([]string{jws, jws}, ProviderFromKey(testHMACKey), BatchOptions{})
+	for ii, result := range results {
+		if result.Err != nil {
+			t.Errorf("Token %d: %v", ii, result.Err)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned when a requested claim is not present in the payload
+var ErrClaimNotFound = errors.New("Claim not found")
+
+// Returned when the "exp" claim is not in the future
+var ErrTokenExpired = errors.New("Token is expired")
+
+// Returned by ValidateConfirmation when the payload has no "cnf.jkt"
+var ErrConfirmationMissing = errors.New("Token has no cnf.jkt confirmation")
+
+// Returned by ValidateConfirmation when "cnf.jkt" names a different key
+var ErrConfirmationMismatch = errors.New("Presented key does not match cnf.jkt")
+
+// Returned when a string claim does not match its configured pattern
+var ErrClaimPatternMismatch = errors.New("Claim does not match required pattern")
+
+// Returned when the "nbf" claim is in the future
+var ErrTokenNotYetValid = errors.New("Token is not yet valid")
+
+// Returned when a numeric claim falls outside its configured range
+var ErrClaimOutOfRange = errors.New("Claim is out of range")
+
+// Returned when the "iss" claim does not match the expected issuer
+var ErrInvalidIssuer = errors.New("Invalid token issuer")
+
+// Returned when the "aud" claim does not include the expected audience
+var ErrInvalidAudience = errors.New("Invalid token audience")
+
+// Returned when the "iat" claim is in the future
+var ErrTokenIssuedInFuture = errors.New("Token is issued in the future")
+
+// Claim checks applied to the payload after its signature is verified
+type ClaimsOptions struct {
+	// Reject tokens without an "exp" claim
+	RequireExpiry bool
+
+	// Reject tokens without an "nbf" claim
+	RequireNotBefore bool
+
+	// When positive, require both "iat" and "nbf" and reject tokens
+	// whose "nbf" is more than this long after "iat", a sign of a
+	// pre-minted token
+	MaxNotBeforeAfterIssued time.Duration
+
+	// Reject tokens whose "exp" claim has passed
+	ValidateExpiry bool
+
+	// Reject tokens whose "nbf" claim is in the future
+	ValidateNotBefore bool
+
+	// Reject tokens whose "iat" claim is in the future
+	ValidateIssuedAt bool
+
+	// Reject tokens carrying both "iat" and "exp" where "exp" is not
+	// strictly after "iat". Such tokens are never valid and point to a
+	// broken or malicious issuer; enabling this is recommended
+	RequireExpiryAfterIssued bool
+
+	// When non-empty, the "iss" claim must equal this value
+	Issuer string
+
+	// When non-empty, the "aud" claim, a string or an array of
+	// strings, must contain this value
+	Audience string
+
+	// Audiences that must all be present in the "aud" claim. A token
+	// missing any of them fails with an *AudienceError
+	RequiredAudiences []string
+
+	// Claims that must be present as strings matching a pattern, for
+	// example "sub" with a UUID expression. Patterns match anywhere in
+	// the value unless anchored with ^ and $
+	ClaimPatterns map[string]*regexp.Regexp
+
+	// Numeric claims that must be present and within a range
+	NumericClaimRanges map[string]NumericRange
+
+	// When positive, VerifyResult.NearExpiry reports tokens whose
+	// "exp" is less than this far from now, so clients can refresh
+	// them before they expire
+	NearExpiryWindow time.Duration
+
+	// Clock skew tolerated by the "exp", "nbf" and "iat" checks
+	Leeway time.Duration
+
+	// Source of the current time; time.Now when nil
+	Now func() time.Time
+
+	// Report every failing claim in a *ClaimsError instead of stopping
+	// at the first
+	CollectAll bool
+}
+
+// Inclusive bounds for a numeric claim. An empty bound is unbounded.
+// Bounds and claim values are compared exactly as decimals, so large
+// integers and fractions do not lose precision
+type NumericRange struct {
+	Min json.Number
+	Max json.Number
+}
+
+// Report whether the JSON number raw lies within the range
+func (nr NumericRange) contains(raw json.RawMessage) (bool, error) {
+	value, ok := new(big.Rat).SetString(string(raw))
+	if !ok {
+		return false, nil
+	}
+	if nr.Min != "" {
+		min, ok := new(big.Rat).SetString(string(nr.Min))
+		if !ok {
+			return false, fmt.Errorf("Invalid numeric claim bound %q", nr.Min)
+		}
+		if value.Cmp(min) < 0 {
+			return false, nil
+		}
+	}
+	if nr.Max != "" {
+		max, ok := new(big.Rat).SetString(string(nr.Max))
+		if !ok {
+			return false, fmt.Errorf("Invalid numeric claim bound %q", nr.Max)
+		}
+		if value.Cmp(max) > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Every claim check that failed for a token, in the order they were
+// checked. Returned when ClaimsOptions.CollectAll is set.
+type ClaimsError struct {
+	Errors []error
+}
+
+func (e *ClaimsError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for ii, err := range e.Errors {
+		msgs[ii] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ClaimsError) Unwrap() []error {
+	return e.Errors
+}
+
+// Returned when the "aud" claim lacks some of
+// ClaimsOptions.RequiredAudiences. Matches ErrInvalidAudience.
+type AudienceError struct {
+	Missing []string
+}
+
+func (e *AudienceError) Error() string {
+	return fmt.Sprintf("%v: missing %s", ErrInvalidAudience, strings.Join(e.Missing, ", "))
+}
+
+func (e *AudienceError) Unwrap() error {
+	return ErrInvalidAudience
+}
+
+func (c *ClaimsOptions) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Report whether the payload's "exp" is within NearExpiryWindow
+func (c *ClaimsOptions) nearExpiry(payload []byte) bool {
+	if c.NearExpiryWindow <= 0 {
+		return false
+	}
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return false
+	}
+	exp, ok, err := numericDateClaim(claims, "exp")
+	if err != nil || !ok {
+		return false
+	}
+	return exp.Sub(c.now()) < c.NearExpiryWindow
+}
+
+func (c *ClaimsOptions) validate(payload []byte) error {
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return err
+	}
+
+	var failures []error
+	fail := func(err error) {
+		failures = append(failures, err)
+	}
+
+	exp, hasExp, err := numericDateClaim(claims, "exp")
+	if err != nil {
+		fail(err)
+	} else if c.RequireExpiry && !hasExp {
+		fail(fmt.Errorf("%w: exp", ErrClaimNotFound))
+	} else if c.ValidateExpiry && hasExp && !c.now().Add(-c.Leeway).Before(exp) {
+		fail(ErrTokenExpired)
+	}
+
+	nbf, hasNbf, err := numericDateClaim(claims, "nbf")
+	if err != nil {
+		fail(err)
+	} else if c.RequireNotBefore && !hasNbf {
+		fail(fmt.Errorf("%w: nbf", ErrClaimNotFound))
+	} else if c.ValidateNotBefore && hasNbf && c.now().Add(c.Leeway).Before(nbf) {
+		fail(ErrTokenNotYetValid)
+	}
+
+	iat, hasIat, err := numericDateClaim(claims, "iat")
+	if err != nil {
+		fail(err)
+	} else if hasIat {
+		// independent checks, so CollectAll reports both
+		if c.RequireExpiryAfterIssued && hasExp && !exp.After(iat) {
+			fail(fmt.Errorf("Token exp %v is not after iat %v", exp.UTC(), iat.UTC()))
+		}
+		if c.ValidateIssuedAt && c.now().Add(c.Leeway).Before(iat) {
+			fail(ErrTokenIssuedInFuture)
+		}
+	}
+
+	if c.MaxNotBeforeAfterIssued > 0 {
+		if !hasIat {
+			fail(fmt.Errorf("%w: iat", ErrClaimNotFound))
+		} else if !hasNbf {
+			fail(fmt.Errorf("%w: nbf", ErrClaimNotFound))
+		} else if gap := nbf.Sub(iat); gap > c.MaxNotBeforeAfterIssued {
+			fail(fmt.Errorf("Token nbf is %v after iat, exceeding %v", gap, c.MaxNotBeforeAfterIssued))
+		}
+	}
+
+	if c.Issuer != "" {
+		var iss string
+		if raw, ok := claims["iss"]; !ok {
+			fail(fmt.Errorf("%w: iss", ErrClaimNotFound))
+		} else if err := json.Unmarshal(raw, &iss); err != nil || iss != c.Issuer {
+			fail(ErrInvalidIssuer)
+		}
+	}
+
+	if c.Audience != "" || len(c.RequiredAudiences) != 0 {
+		if raw, ok := claims["aud"]; !ok {
+			fail(fmt.Errorf("%w: aud", ErrClaimNotFound))
+		} else {
+			audiences := decodeAudiences(raw)
+			if c.Audience != "" && !containsString(audiences, c.Audience) {
+				fail(ErrInvalidAudience)
+			}
+			var missing []string
+			for _, aud := range c.RequiredAudiences {
+				if !containsString(audiences, aud) {
+					missing = append(missing, aud)
+				}
+			}
+			if len(missing) != 0 {
+				fail(&AudienceError{Missing: missing})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(c.ClaimPatterns))
+	for name := range c.ClaimPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var value string
+		if raw, ok := claims[name]; !ok {
+			fail(fmt.Errorf("%w: %s", ErrClaimNotFound, name))
+		} else if err := json.Unmarshal(raw, &value); err != nil || !c.ClaimPatterns[name].MatchString(value) {
+			fail(fmt.Errorf("%w: %s", ErrClaimPatternMismatch, name))
+		}
+	}
+
+	names = names[:0]
+	for name := range c.NumericClaimRanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw, ok := claims[name]
+		if !ok {
+			fail(fmt.Errorf("%w: %s", ErrClaimNotFound, name))
+			continue
+		}
+		// JSON numbers are a subset of what big.Rat parses; anything
+		// else, a quoted string included, fails to parse
+		if inRange, err := c.NumericClaimRanges[name].contains(raw); err != nil {
+			fail(err)
+		} else if !inRange {
+			fail(fmt.Errorf("%w: %s", ErrClaimOutOfRange, name))
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	if !c.CollectAll {
+		return failures[0]
+	}
+	return &ClaimsError{Errors: failures}
+}
+
+// Verify the JWS, check its "exp" and "nbf" claims against the current
+// time and unmarshal the JSON payload into claims. Fails with
+// ErrTokenExpired or ErrTokenNotYetValid when the token is outside its
+// validity period.
+func VerifyAndDecodeClaims(jws string, kp KeyProvider, claims interface{}) error {
+	result, err := VerifyWithOptions(jws, kp, VerifyOptions{
+		Claims: &ClaimsOptions{ValidateExpiry: true, ValidateNotBefore: true},
+	})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(result.Payload, claims); err != nil {
+		return fmt.Errorf("Failed to decode claims: %v", err)
+	}
+	return nil
+}
+
+// Verify the JWS and check its claims as configured by opts, returning
+// the payload. Failures can be told apart with errors.Is against
+// ErrTokenExpired, ErrTokenNotYetValid, ErrInvalidAudience and the
+// other claim errors.
+func VerifyAndDecodeWithClaims(jws string, kp KeyProvider, opts ClaimsOptions) ([]byte, error) {
+	result, err := VerifyWithOptions(jws, kp, VerifyOptions{Claims: &opts})
+	if err != nil {
+		return nil, err
+	}
+	return result.Payload, nil
+}
+
+// Decode an "aud" claim, either a single string or an array of strings
+// (RFC 7519 4.1.3). Any other value yields no audiences
+func decodeAudiences(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}
+	}
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err != nil {
+		return nil
+	}
+	return multiple
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify the JWS, then follow path through the JSON payload and return
+// the raw value found there. Path elements select object members by
+// name and array elements by decimal index.
+func VerifyAndGetClaimPath(jws string, kp KeyProvider, path ...string) (json.RawMessage, error) {
+	payload, err := VerifyAndDecode(jws, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	value := json.RawMessage(payload)
+	for ii, elem := range path {
+		trimmed := bytes.TrimLeft(value, " \t\r\n")
+		if len(trimmed) == 0 {
+			return nil, errors.New("Failed to decode claims: empty value")
+		}
+
+		switch trimmed[0] {
+		case '{':
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(value, &obj); err != nil {
+				return nil, fmt.Errorf("Failed to decode claims: %v", err)
+			}
+
+			next, ok := obj[elem]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrClaimNotFound, claimPathString(path[:ii+1]))
+			}
+			value = next
+
+		case '[':
+			var arr []json.RawMessage
+			if err := json.Unmarshal(value, &arr); err != nil {
+				return nil, fmt.Errorf("Failed to decode claims: %v", err)
+			}
+
+			index, err := strconv.Atoi(elem)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("Claim %s is an array, %q is not an index", claimPathString(path[:ii]), elem)
+			}
+			if index >= len(arr) {
+				return nil, fmt.Errorf("%w: %s", ErrClaimNotFound, claimPathString(path[:ii+1]))
+			}
+			value = arr[index]
+
+		default:
+			return nil, fmt.Errorf("Claim %s is not an object or array", claimPathString(path[:ii]))
+		}
+	}
+
+	return value, nil
+}
+
+// Verify the JWS and return every top-level claim whose name begins
+// with prefix, such as namespaced claims under a URL
+func VerifyAndGetClaimsByPrefix(jws string, kp KeyProvider, prefix string) (map[string]json.RawMessage, error) {
+	payload, err := VerifyAndDecode(jws, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]json.RawMessage)
+	for name, value := range claims {
+		if strings.HasPrefix(name, prefix) {
+			matched[name] = value
+		}
+	}
+	return matched, nil
+}
+
+func claimPathString(path []string) string {
+	if len(path) == 0 {
+		return "<payload>"
+	}
+	return strings.Join(path, ".")
+}
+
+// Check that the "cnf.jkt" claim (RFC 7800, RFC 9449) of a verified
+// JSON payload is the RFC 7638 thumbprint of presentedKey, binding the
+// token to the holder of that key.
+func ValidateConfirmation(claims []byte, presentedKey crypto.PublicKey) error {
+	var payload struct {
+		Cnf *struct {
+			Jkt string `json:"jkt"`
+		} `json:"cnf"`
+	}
+	if err := json.Unmarshal(claims, &payload); err != nil {
+		return fmt.Errorf("Failed to decode claims: %v", err)
+	}
+	if payload.Cnf == nil || payload.Cnf.Jkt == "" {
+		return ErrConfirmationMissing
+	}
+
+	thumbprint, err := Thumbprint(presentedKey)
+	if err != nil {
+		return err
+	}
+	if thumbprint != payload.Cnf.Jkt {
+		return ErrConfirmationMismatch
+	}
+	return nil
+}
+
+// Expiration time ("exp") of the verified token. ok is false, and exp
+// the zero time, when the token carries no expiry.
+func (r VerifyResult) Expiry() (exp time.Time, ok bool, err error) {
+	claims, err := decodeClaims(r.Payload)
+	if err != nil {
+		return
+	}
+	return numericDateClaim(claims, "exp")
+}
+
+func decodeClaims(payload []byte) (map[string]json.RawMessage, error) {
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("Failed to decode claims: %v", err)
+	}
+	return claims, nil
+}
+
+// read a NumericDate claim (seconds since the epoch, possibly fractional)
+func numericDateClaim(claims map[string]json.RawMessage, name string) (t time.Time, ok bool, err error) {
+	raw, present := claims[name]
+	if !present || string(raw) == "null" {
+		return
+	}
+
+	var seconds float64
+	if err = json.Unmarshal(raw, &seconds); err != nil {
+		err = fmt.Errorf("Malformed %s claim: %s", name, raw)
+		return
+	}
+
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)), true, nil
+}
@@ -26,14 +26,37 @@ package gojws
 
 import (
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"strings"
 )
 
+// decode unpadded base64url (RFC 7515 2). Padding, characters outside
+// the URL-safe alphabet and non-zero trailing bits are all rejected
 func safeDecode(str string) ([]byte, error) {
-	lenMod4 := len(str) % 4
-	if lenMod4 > 0 {
-		str = str + strings.Repeat("=", 4-lenMod4)
+	if i := strings.IndexFunc(str, notBase64URL); i != -1 {
+		if str[i] == '=' {
+			return nil, errors.New("Padding is not allowed in base64url")
+		}
+		return nil, fmt.Errorf("Invalid base64url character %q at offset %d", str[i], i)
 	}
+	return base64.RawURLEncoding.Strict().DecodeString(str)
+}
+
+// decode standard (non-URL) base64, unpadded or correctly padded. As
+// with safeDecode, non-zero trailing bits are rejected so that a token
+// has a single encoding
+func stdDecode(str string) ([]byte, error) {
+	// the decoders skip newlines, so check the alphabet first
+	if i := strings.IndexFunc(str, notStdBase64); i != -1 {
+		return nil, fmt.Errorf("Invalid base64 character %q at offset %d", str[i], i)
+	}
+	if strings.HasSuffix(str, "=") {
+		return base64.StdEncoding.Strict().DecodeString(str)
+	}
+	return base64.RawStdEncoding.Strict().DecodeString(str)
+}
 
-	return base64.URLEncoding.DecodeString(str)
+func notStdBase64(r rune) bool {
+	return !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '/' || r == '=')
 }
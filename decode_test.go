@@ -0,0 +1,104 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSafeDecode(t *testing.T) {
+	data, err := safeDecode("eyJpc3MiOiJqb2UifQ")
+	if err != nil {
+		t.Fatal("safeDecode: ", err)
+	}
+	if string(data) != `{"iss":"joe"}` {
+		t.Fatalf("Unexpected data: %s", data)
+	}
+
+	for _, segment := range []string{
+		"eyJpc3MiOiJqb2UifQ==",
+		"eyJpc3MiOiJqb2UifQ=",
+		"c2l+",
+		"c2l/",
+		"c2ln\n",
+		"c2 ln",
+		"c2l", // non-zero trailing bits
+		"c",
+	} {
+		if _, err := safeDecode(segment); err == nil {
+			t.Fatalf("safeDecode accepted %q", segment)
+		}
+	}
+}
+
+func TestVerify_MalformedSegments(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	parts := strings.Split(jws, ".")
+
+	tests := map[string]string{
+		"too few parts":         parts[0] + "." + parts[1],
+		"too many parts":        jws + "." + parts[2],
+		"padded header":         parts[0] + "=." + parts[1] + "." + parts[2],
+		"padded payload":        parts[0] + "." + parts[1] + "==." + parts[2],
+		"padded signature":      jws + "=",
+		"standard alphabet":     parts[0] + "." + parts[1] + "." + strings.NewReplacer("-", "+", "_", "/").Replace(parts[2]) + "+/",
+		"invalid character":     parts[0] + "*." + parts[1] + "." + parts[2],
+		"whitespace in payload": parts[0] + "." + parts[1][:4] + "\n" + parts[1][4:] + "." + parts[2],
+	}
+	for name, jws := range tests {
+		if _, err := VerifyAndDecode(jws, ProviderFromKey(testHMACKey)); !errors.Is(err, ErrMalformedJWS) {
+			t.Fatalf("%s: expected ErrMalformedJWS, got %v", name, err)
+		}
+	}
+}
+
+func TestVerify_MissingAlg(t *testing.T) {
+	jws := signHS256(`{"typ":"JWT"}`, `{"iss":"joe"}`, testHMACKey)
+	kp := ProviderFromKey(testHMACKey)
+
+	if _, err := VerifyAndDecode(jws, kp); !errors.Is(err, ErrMissingAlg) {
+		t.Fatalf("Verify: expected ErrMissingAlg, got %v", err)
+	}
+	if _, err := PrecomputeToken(jws); !errors.Is(err, ErrMissingAlg) {
+		t.Fatalf("PrecomputeToken: expected ErrMissingAlg, got %v", err)
+	}
+	sv, err := NewStreamVerifier(kp, VerifyOptions{})
+	if err != nil {
+		t.Fatal("NewStreamVerifier: ", err)
+	}
+	if _, _, err := sv.Verify(jws); !errors.Is(err, ErrMissingAlg) {
+		t.Fatalf("StreamVerifier: expected ErrMissingAlg, got %v", err)
+	}
+	parts := strings.Split(jws, ".")
+	signature, err := safeDecode(parts[2])
+	if err != nil {
+		t.Fatal("safeDecode: ", err)
+	}
+	if err := VerifyParts(parts[0]+"."+parts[1], signature, kp); !errors.Is(err, ErrMissingAlg) {
+		t.Fatalf("VerifyParts: expected ErrMissingAlg, got %v", err)
+	}
+}
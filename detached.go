@@ -0,0 +1,101 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Verify a signature supplied separately from its "header.payload"
+// signing input, as when the JWS signing input is the signature base
+// of another protocol. The header is decoded from signingInput and
+// used to acquire the key; the payload is never decoded.
+func VerifyParts(signingInput string, signature []byte, kp KeyProvider) (err error) {
+	headerSegment, payload, ok := strings.Cut(signingInput, ".")
+	if !ok || headerSegment == "" {
+		err = ErrMalformedJWS
+		return
+	}
+
+	opts := &VerifyOptions{}
+	header, err := decodeProtectedHeader(headerSegment, safeDecode, opts)
+	if err != nil {
+		return
+	}
+	defer wrapVerificationError(&err, header)
+
+	if header.Alg == "" {
+		err = ErrMissingAlg
+		return
+	}
+	if header.payloadEncoded() && strings.Contains(payload, ".") {
+		err = ErrMalformedJWS
+		return
+	}
+
+	key, err := acquireKey(kp, header, opts)
+	if err != nil {
+		return
+	}
+	sv, err := newSignatureVerifier(header.Alg, key, opts)
+	if err != nil {
+		return
+	}
+	writeSigningInput(sv, nil, headerSegment, payload)
+	err = sv.verify(signature)
+	return
+}
+
+// Verify a compact JWS with detached content (RFC 7515 F), whose
+// payload segment is empty and whose payload is supplied separately.
+// The payload is base64url encoded to rebuild the signing input unless
+// the header sets "b64" to false (RFC 7797).
+func VerifyDetached(jws string, payload []byte, kp KeyProvider) error {
+	parts, ok := splitCompact(jws)
+	if !ok {
+		return ErrMalformedJWS
+	}
+	if parts[1] != "" {
+		return errors.New("Detached JWS must not carry a payload")
+	}
+
+	header, err := decodeProtectedHeader(parts[0], safeDecode, &VerifyOptions{})
+	if err != nil {
+		return err
+	}
+	signature, err := safeDecode(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w signature: %v", ErrMalformedJWS, err)
+	}
+
+	encoded := string(payload)
+	if header.payloadEncoded() {
+		encoded = base64.RawURLEncoding.EncodeToString(payload)
+	}
+	return VerifyParts(parts[0]+"."+encoded, signature, kp)
+}
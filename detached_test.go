@@ -0,0 +1,112 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyParts(t *testing.T) {
+	for _, tc := range interopTokens {
+		key, err := keyFromJWK(tc.key)
+		if err != nil {
+			t.Fatalf("%s: keyFromJWK: %v", tc.alg, err)
+		}
+		kp := ProviderFromKey(key)
+
+		end := strings.LastIndexByte(tc.jws, '.')
+		signingInput := tc.jws[:end]
+		signature, err := base64.RawURLEncoding.DecodeString(tc.jws[end+1:])
+		if err != nil {
+			t.Fatalf("%s: Malformed fixture signature: %v", tc.alg, err)
+		}
+
+		if err := VerifyParts(signingInput, signature, kp); err != nil {
+			t.Errorf("%s: VerifyParts: %v", tc.alg, err)
+		}
+		if err := VerifyParts(signingInput+"x", signature, kp); err == nil {
+			t.Errorf("%s: VerifyParts succeeded with altered signing input", tc.alg)
+		}
+	}
+}
+
+func TestVerifyParts_Malformed(t *testing.T) {
+	kp := ProviderFromKey(testHMACKey)
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	parts := strings.Split(jws, ".")
+
+	for _, signingInput := range []string{parts[0], "." + parts[1], parts[0] + "." + parts[1] + "." + parts[1]} {
+		if err := VerifyParts(signingInput, []byte(parts[2]), kp); !errors.Is(err, ErrMalformedJWS) {
+			t.Fatalf("%q: expected ErrMalformedJWS, got %v", signingInput, err)
+		}
+	}
+}
+
+func TestVerifyDetached(t *testing.T) {
+	payload := []byte(`{"event":"invoice.paid","amount":1200}`)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	for _, tt := range []struct {
+		jws string
+		kp  KeyProvider
+	}{
+		{signHS256(`{"alg":"HS256"}`, string(payload), testHMACKey), ProviderFromKey(testHMACKey)},
+		{signES256(t, key, `{"alg":"ES256"}`, string(payload)), ProviderFromKey(&key.PublicKey)},
+	} {
+		parts := strings.Split(tt.jws, ".")
+		detached := parts[0] + ".." + parts[2]
+
+		if err := VerifyDetached(detached, payload, tt.kp); err != nil {
+			t.Fatal("VerifyDetached: ", err)
+		}
+		if err := VerifyDetached(detached, []byte(`{"event":"invoice.paid","amount":9200}`), tt.kp); err == nil {
+			t.Fatal("VerifyDetached succeeded with a tampered payload")
+		}
+		if err := VerifyDetached(tt.jws, payload, tt.kp); err == nil {
+			t.Fatal("VerifyDetached succeeded with an attached payload")
+		}
+	}
+}
+
+func TestVerifyDetached_Unencoded(t *testing.T) {
+	// RFC 7797 4.2 - the detached "$.02" example
+	const jws = `eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY`
+
+	if err := VerifyDetached(jws, []byte("$.02"), ProviderFromKey(testHMACKey)); err != nil {
+		t.Fatal("VerifyDetached: ", err)
+	}
+	if err := VerifyDetached(jws, []byte("$.03"), ProviderFromKey(testHMACKey)); err == nil {
+		t.Fatal("VerifyDetached succeeded with a tampered payload")
+	}
+}
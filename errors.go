@@ -0,0 +1,71 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+)
+
+// Failure to verify a token whose header could be decoded. Alg and Kid
+// come from the unverified header and are meant for diagnostics such as
+// counting rejections; they must not be trusted. Err is the underlying
+// cause, available to errors.Is and errors.As.
+type VerificationError struct {
+	Alg Algorithm
+	Kid string
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Returned by a KeyProvider when its keys could not be retrieved, for
+// example because a JWKS endpoint was unreachable. Distinguishes an
+// outage from a key that does not exist.
+type KeyFetchError struct {
+	Err error
+}
+
+func (e *KeyFetchError) Error() string {
+	return "Failed to fetch keys: " + e.Err.Error()
+}
+
+func (e *KeyFetchError) Unwrap() error {
+	return e.Err
+}
+
+// Attach header details to *errp, once
+func wrapVerificationError(errp *error, header Header) {
+	var verr *VerificationError
+	if *errp == nil || errors.As(*errp, &verr) {
+		return
+	}
+	*errp = &VerificationError{Alg: header.Alg, Kid: header.Kid, Err: *errp}
+}
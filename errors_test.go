@@ -0,0 +1,105 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify_UnknownAlgorithmError(t *testing.T) {
+	jws := signHS256(`{"alg":"XS999","kid":"key-7"}`, `{"iss":"joe"}`, testHMACKey)
+
+	_, err := VerifyAndDecode(jws, ProviderFromKey(testHMACKey))
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *VerificationError, got %T: %v", err, err)
+	}
+	if verr.Alg != "XS999" || verr.Kid != "key-7" {
+		t.Fatalf("Unexpected error fields: %+v", verr)
+	}
+}
+
+func TestVerify_VerificationErrorFields(t *testing.T) {
+	// signature failures and revoked keys carry the header details too,
+	// and still match their sentinels
+	jws := signHS256(`{"alg":"HS256","kid":"key-8"}`, `{"iss":"joe"}`, []byte("other key"))
+
+	_, err := VerifyAndDecode(jws, ProviderFromKey(testHMACKey))
+	var verr *VerificationError
+	if !errors.As(err, &verr) || verr.Alg != ALG_HS256 || verr.Kid != "key-8" {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	_, err = VerifyWithOptions(jws, ProviderFromKey(testHMACKey), VerifyOptions{
+		IsRevokedKid: func(kid string) bool { return true },
+	})
+	if !errors.Is(err, ErrKeyRevoked) || !errors.As(err, &verr) || verr.Kid != "key-8" {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	// errors before the header is decoded have no details to carry
+	_, err = VerifyAndDecode("not a jws", ProviderFromKey(testHMACKey))
+	if err == nil || errors.As(err, &verr) {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+}
+
+func TestVerify_OpaqueSignatureFailures(t *testing.T) {
+	for _, tc := range interopTokens {
+		key, err := keyFromJWK(tc.key)
+		if err != nil {
+			t.Fatalf("%s: keyFromJWK: %v", tc.alg, err)
+		}
+		kp := ProviderFromKey(key)
+		parts := strings.Split(tc.jws, ".")
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatalf("%s: Malformed fixture signature: %v", tc.alg, err)
+		}
+
+		flipped := append([]byte{}, signature...)
+		flipped[len(flipped)/2] ^= 0x01
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"node","sub":"interop","n":2}`))
+		for name, jws := range map[string]string{
+			"bad signature":    parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(flipped),
+			"tampered payload": parts[0] + "." + payload + "." + parts[2],
+			"short signature":  parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature[:len(signature)-1]),
+		} {
+			_, err := VerifyAndDecode(jws, kp)
+			if !errors.Is(err, ErrVerificationFailed) || err.Error() != ErrVerificationFailed.Error() {
+				t.Fatalf("%s %s: expected ErrVerificationFailed, got %v", tc.alg, name, err)
+			}
+		}
+	}
+
+	// a misconfigured key is reported as such
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	if _, err := VerifyAndDecode(jws, ProviderFromKey("not a key")); err == nil || errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("Expected a key type error, got %v", err)
+	}
+}
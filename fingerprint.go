@@ -0,0 +1,74 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// Compute a stable identifier for an asymmetric key suitable for
+// logging: the unpadded base64url SHA-256 of its PKIX (SPKI) DER
+// encoding. Private keys are fingerprinted by their public half.
+func KeyFingerprint(key crypto.PublicKey) (string, error) {
+	key, err := publicHalf(key)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("Cannot fingerprint key of type %T", key)
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// The public half of a private key, or key itself. A malformed Ed25519
+// private key is rejected rather than left to panic in Public
+func publicHalf(key crypto.PublicKey) (crypto.PublicKey, error) {
+	if k, ok := key.(ed25519.PrivateKey); ok && len(k) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("Malformed Ed25519 private key of %d bytes", len(k))
+	}
+	if priv, ok := key.(interface {
+		Public() crypto.PublicKey
+	}); ok {
+		return priv.Public(), nil
+	}
+	return key, nil
+}
+
+// Derive a cache key for a token: the unpadded base64url SHA-256 of its
+// exact text. Tokens differing in any byte, including encoding details
+// that do not change the signed content, get different keys.
+func CacheKey(jws string) string {
+	sum := sha256.Sum256([]byte(jws))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Unverified view of a compact JWS for debugging and tooling. Nothing
+// here is authenticated; use VerifyAndDecode before trusting a token.
+type Inspection struct {
+	Header  Header
+	Payload []byte
+
+	// Advisory: the payload is itself base64url of a JSON document,
+	// which usually means the producer encoded it twice. Payload is
+	// returned as carried in the token regardless.
+	PayloadDoubleEncoded bool
+}
+
+// Decode a compact JWS without verifying its signature
+func Inspect(jws string) (info Inspection, err error) {
+	parts, ok := splitCompact(jws)
+	if !ok {
+		err = ErrMalformedJWS
+		return
+	}
+
+	info.Header, err = decodeProtectedHeader(parts[0], safeDecode, &VerifyOptions{})
+	if err != nil {
+		return
+	}
+
+	if !info.Header.payloadEncoded() {
+		info.Payload = []byte(parts[1])
+		return
+	}
+	info.Payload, err = safeDecode(parts[1])
+	if err != nil {
+		err = fmt.Errorf("%w payload: %v", ErrMalformedJWS, err)
+		return
+	}
+
+	info.PayloadDoubleEncoded = looksDoubleEncoded(info.Payload)
+	return
+}
+
+// Is the payload the base64url (padded or not) encoding of a JSON
+// object or array
+func looksDoubleEncoded(payload []byte) bool {
+	trimmed := bytes.TrimRight(bytes.TrimSpace(payload), "=")
+	if len(trimmed) == 0 {
+		return false
+	}
+
+	inner := make([]byte, base64.RawURLEncoding.DecodedLen(len(trimmed)))
+	n, err := base64.RawURLEncoding.Decode(inner, trimmed)
+	if err != nil {
+		return false
+	}
+	inner = bytes.TrimSpace(inner[:n])
+
+	if len(inner) == 0 || (inner[0] != '{' && inner[0] != '[') {
+		return false
+	}
+	return json.Valid(inner)
+}
+
+// UNSAFE, DO NOT USE IN PRODUCTION: decode an unsecured ("alg":"none")
+// JWS. Such tokens carry no signature, so anyone can forge them; this
+// exists only for local development tooling. Tokens with any other
+// algorithm, or with a non-empty signature, are rejected.
+func DecodeUnsafeNone(jws string) (payload []byte, header Header, err error) {
+	parts, ok := splitCompact(jws)
+	if !ok {
+		err = ErrMalformedJWS
+		return
+	}
+	if parts[2] != "" {
+		err = errors.New("Unsecured JWS must have an empty signature")
+		return
+	}
+
+	result, err := VerifyWithOptions(jws, ProviderFromKey(NoneKey), VerifyOptions{})
+	if err != nil {
+		return
+	}
+	if result.Header.Alg != ALG_NONE {
+		err = fmt.Errorf("Expected alg none. Got %s", result.Header.Alg)
+		return
+	}
+	return result.Payload, result.Header, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Key providers for a multi-tenant verifier, keyed by issuer.
+//
+// The provider is chosen by the "iss" claim of the payload before its
+// signature is checked, so an attacker controls which entry is used.
+// This is only safe because each provider holds nothing but its own
+// issuer's keys and selects among them by the header (e.g. by "kid"):
+// a token claiming issuer A is then verified only against A's keys. A
+// provider shared between issuers, or one ignoring the header, would
+// let one tenant mint tokens for another. Once verified, the payload's
+// "iss" is confirmed again against the entry that was used.
+type IssuerRegistry map[string]KeyProvider
+
+// Verify a compact JWS with the key provider registered for its "iss"
+func (r IssuerRegistry) Verify(jws string, opts VerifyOptions) (result VerifyResult, err error) {
+	if opts.PreProcess != nil {
+		jws, err = opts.PreProcess(jws)
+		if err != nil {
+			err = fmt.Errorf("Failed to preprocess JWS: %w", err)
+			return
+		}
+		opts.PreProcess = nil
+	}
+
+	// untrusted until verified; only used to pick a provider
+	info, err := Inspect(jws)
+	if err != nil {
+		return
+	}
+	issuer, err := issuerClaim(info.Payload)
+	if err != nil {
+		return
+	}
+	kp, ok := r[issuer]
+	if !ok {
+		err = fmt.Errorf("%w: %q is not registered", ErrInvalidIssuer, issuer)
+		return
+	}
+
+	result, err = VerifyWithOptions(jws, kp, opts)
+	if err != nil {
+		return
+	}
+
+	verified, err := issuerClaim(result.Payload)
+	if err != nil {
+		return
+	}
+	if verified != issuer {
+		err = fmt.Errorf("%w: verified %q, selected %q", ErrInvalidIssuer, verified, issuer)
+	}
+	return
+}
+
+func issuerClaim(payload []byte) (string, error) {
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return "", err
+	}
+	raw, ok := claims["iss"]
+	if !ok {
+		return "", fmt.Errorf("%w: iss", ErrClaimNotFound)
+	}
+	var iss string
+	if err := json.Unmarshal(raw, &iss); err != nil {
+		return "", ErrInvalidIssuer
+	}
+	return iss, nil
+}
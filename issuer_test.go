@@ -0,0 +1,62 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIssuerRegistry(t *testing.T) {
+	keyA, keyB := []byte("tenant a signing key"), []byte("tenant b signing key")
+	registry := IssuerRegistry{
+		"https://a.example.com": ProviderFromKey(keyA),
+		"https://b.example.com": ProviderFromKey(keyB),
+	}
+
+	tokenA := signHS256(`{"alg":"HS256"}`, `{"iss":"https://a.example.com","sub":"alice"}`, keyA)
+	tokenB := signHS256(`{"alg":"HS256"}`, `{"iss":"https://b.example.com","sub":"bob"}`, keyB)
+	for _, jws := range []string{tokenA, tokenB} {
+		if _, err := registry.Verify(jws, VerifyOptions{}); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+
+	// tenant A cannot mint tokens claiming to be tenant B
+	forged := signHS256(`{"alg":"HS256"}`, `{"iss":"https://b.example.com","sub":"alice"}`, keyA)
+	if _, err := registry.Verify(forged, VerifyOptions{}); err == nil {
+		t.Fatal("Verify accepted a token signed by another issuer's key")
+	}
+
+	unknown := signHS256(`{"alg":"HS256"}`, `{"iss":"https://c.example.com"}`, keyA)
+	if _, err := registry.Verify(unknown, VerifyOptions{}); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("Expected ErrInvalidIssuer, got %v", err)
+	}
+
+	missing := signHS256(`{"alg":"HS256"}`, `{"sub":"alice"}`, keyA)
+	if _, err := registry.Verify(missing, VerifyOptions{}); !errors.Is(err, ErrClaimNotFound) {
+		t.Fatalf("Expected ErrClaimNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Flattened JWS JSON Serialization (RFC 7515 7.2.2)
+type flattenedJWS struct {
+	Payload   *string         `json:"payload,omitempty"`
+	Protected string          `json:"protected"`
+	Header    json.RawMessage `json:"header,omitempty"`
+	Signature string          `json:"signature"`
+}
+
+func parseFlattenedJWS(data []byte) (*flattenedJWS, error) {
+	var jws flattenedJWS
+	if err := json.Unmarshal(data, &jws); err != nil {
+		return nil, fmt.Errorf("Malformed JWS JSON serialization: %v", err)
+	}
+	if jws.Protected == "" {
+		return nil, errors.New("Malformed JWS JSON serialization: missing protected header")
+	}
+	return &jws, nil
+}
+
+// Decode the JOSE header: the protected header combined with the
+// unprotected one. The two must not share any parameter names, and
+// "alg" must be protected (RFC 7515 4.1.1).
+func (jws *flattenedJWS) decodeHeader(opts *VerifyOptions) (header Header, err error) {
+	header, err = decodeProtectedHeader(jws.Protected, safeDecode, opts)
+	if err != nil {
+		return
+	}
+	if header.Alg == "" {
+		err = ErrMissingAlg
+		return
+	}
+	if len(jws.Header) == 0 {
+		return
+	}
+
+	data, err := safeDecode(jws.Protected)
+	if err != nil {
+		return
+	}
+	var protected, unprotected map[string]json.RawMessage
+	if err = json.Unmarshal(data, &protected); err != nil {
+		err = fmt.Errorf("Failed to decode header: %v", err)
+		return
+	}
+	if err = json.Unmarshal(jws.Header, &unprotected); err != nil {
+		err = fmt.Errorf("Failed to decode unprotected header: %v", err)
+		return
+	}
+
+	for name := range unprotected {
+		if _, ok := protected[name]; ok {
+			err = fmt.Errorf("Duplicate JWS header parameter: %s", name)
+			return
+		}
+		if name == "crit" || name == "b64" {
+			err = fmt.Errorf("JWS header parameter must be integrity protected: %s", name)
+			return
+		}
+	}
+
+	err = json.Unmarshal(jws.Header, &header)
+	if err != nil {
+		err = fmt.Errorf("Failed to decode unprotected header: %v", err)
+	}
+	return
+}
+
+// Verify a flattened JSON serialization JWS whose payload is detached
+// and supplied by a reader. The payload is hashed as it is read and
+// never held in memory, so arbitrarily large payloads can be verified.
+func VerifyDetachedJSONReader(envelope []byte, payload io.Reader, kp KeyProvider) (header Header, err error) {
+	jws, err := parseFlattenedJWS(envelope)
+	if err != nil {
+		return
+	}
+	if jws.Payload != nil && *jws.Payload != "" {
+		err = errors.New("Detached JWS must not carry a payload")
+		return
+	}
+
+	opts := &VerifyOptions{}
+	header, err = jws.decodeHeader(opts)
+	if err != nil {
+		return
+	}
+	defer wrapVerificationError(&err, header)
+
+	// acquire the public key
+	key, err := acquireKey(kp, header, opts)
+	if err != nil {
+		return
+	}
+
+	signature, err := safeDecode(jws.Signature)
+	if err != nil {
+		err = fmt.Errorf("%w signature: %v", ErrMalformedJWS, err)
+		return
+	}
+
+	sv, err := newSignatureVerifier(header.Alg, key, opts)
+	if err != nil {
+		return
+	}
+
+	// stream the payload into the signing input, encoding as we go
+	// unless it is unencoded (RFC 7797). The protected header is used
+	// exactly as received; authenticated data is never re-serialized
+	writeSigningInput(sv, opts.SigningContext, jws.Protected, "")
+	if header.payloadEncoded() {
+		encoder := base64.NewEncoder(base64.RawURLEncoding, sv)
+		if _, err = io.Copy(encoder, payload); err == nil {
+			err = encoder.Close()
+		}
+	} else {
+		_, err = io.Copy(sv, payload)
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to read JWS payload: %v", err)
+		return
+	}
+
+	err = sv.verify(signature)
+	return
+}
+
+// Convert a JWS in compact or flattened JSON serialization to compact
+// serialization. The signature is not verified. JSON members with no
+// compact equivalent, such as an unprotected header, are rejected, as
+// is a general serialization with multiple signatures.
+func Normalize(input []byte) (string, error) {
+	input = bytes.TrimSpace(input)
+	if len(input) == 0 || input[0] != '{' {
+		parts, ok := splitCompact(string(input))
+		if !ok {
+			return "", ErrMalformedJWS
+		}
+		return normalizeParts(parts[0], parts[1], parts[2])
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(input, &members); err != nil {
+		return "", fmt.Errorf("Malformed JWS JSON serialization: %v", err)
+	}
+	for name := range members {
+		switch name {
+		case "payload", "protected", "signature":
+		default:
+			return "", fmt.Errorf("JWS JSON member cannot be represented in compact form: %s", name)
+		}
+	}
+
+	jws, err := parseFlattenedJWS(input)
+	if err != nil {
+		return "", err
+	}
+	payload := ""
+	if jws.Payload != nil {
+		payload = *jws.Payload
+	}
+	return normalizeParts(jws.Protected, payload, jws.Signature)
+}
+
+// Check and join the segments of a compact JWS
+func normalizeParts(protected, payload, signature string) (string, error) {
+	header, err := decodeProtectedHeader(protected, safeDecode, &VerifyOptions{})
+	if err != nil {
+		return "", err
+	}
+	if header.payloadEncoded() {
+		if _, err := safeDecode(payload); err != nil {
+			return "", fmt.Errorf("%w payload: %v", ErrMalformedJWS, err)
+		}
+	} else if strings.Contains(payload, ".") {
+		return "", ErrUnencodedPayloadDot
+	}
+	if _, err := safeDecode(signature); err != nil {
+		return "", fmt.Errorf("%w signature: %v", ErrMalformedJWS, err)
+	}
+	return protected + "." + payload + "." + signature, nil
+}
@@ -0,0 +1,332 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Public parameters of a JSON Web Key (RFC 7517)
+type jwkParams struct {
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// A public key together with the usage restrictions of the JWK it came
+// from. A KeyProvider may return a *JWK from GetJWSKey, in which case
+// the key is only used if it permits verification: "key_ops", when
+// present, must include "verify", "use", when present, must be "sig",
+// and "alg", when present, must match the token's algorithm.
+type JWK struct {
+	Key    crypto.PublicKey `json:"-"`
+	Kid    string           `json:"kid,omitempty"`
+	Alg    Algorithm        `json:"alg,omitempty"`
+	Use    string           `json:"use,omitempty"`
+	KeyOps []string         `json:"key_ops,omitempty"`
+
+	// declared "crv" of a JWK Set entry on an EC curve this package
+	// cannot represent. Key is nil and verification fails with
+	// ErrCurveMismatch
+	unsupportedCrv string
+}
+
+// Check that the key may verify a signature made with alg
+func (k *JWK) permitsVerify(alg Algorithm) error {
+	if k.Key == nil && k.unsupportedCrv != "" {
+		if curve, ok := ecdsaCurves[alg]; ok {
+			return fmt.Errorf("%w: %s requires %s, JWK is %s", ErrCurveMismatch, alg, curve.Params().Name, k.unsupportedCrv)
+		}
+		return fmt.Errorf("Unsupported EC JWK curve: %q", k.unsupportedCrv)
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return fmt.Errorf("JWK use %q does not permit verification", k.Use)
+	}
+	if k.KeyOps != nil {
+		permitted := false
+		for _, op := range k.KeyOps {
+			if op == "verify" {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return fmt.Errorf("JWK key_ops %q do not permit verification", k.KeyOps)
+		}
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return fmt.Errorf("JWK is for %s, not %s", k.Alg, alg)
+	}
+	return nil
+}
+
+// Public keys of a JWK Set (RFC 7517 5)
+type jwkSet []*JWK
+
+// Decode a JWK Set. Keys this package cannot use, such as symmetric
+// keys, are skipped rather than failing the set. EC keys on unsupported
+// curves are kept without a Key, so that selecting one reports
+// ErrCurveMismatch rather than an unknown kid
+func parseJWKSet(data []byte) (jwkSet, error) {
+	var doc struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK Set: %v", err)
+	}
+
+	var set jwkSet
+	for _, raw := range doc.Keys {
+		if jwk, err := parseJWKWithUsage(raw); err == nil {
+			set = append(set, jwk)
+		} else if jwk := parseUnsupportedCurve(raw); jwk != nil {
+			set = append(set, jwk)
+		}
+	}
+	return set, nil
+}
+
+// Select the key named by the header's "kid". A token without a kid
+// only matches a set holding a single key
+func (set jwkSet) lookup(h Header) (*JWK, bool) {
+	if h.Kid == "" {
+		if len(set) == 1 {
+			return set[0], true
+		}
+		return nil, false
+	}
+	for _, jwk := range set {
+		if jwk.Kid == h.Kid {
+			return jwk, true
+		}
+	}
+	return nil, false
+}
+
+// Create a provider selecting keys from a JWK Set document by the
+// header's "kid". Keys are returned as *JWK, so their "use", "key_ops"
+// and "alg" restrictions are enforced
+func KeyProviderFromJWKS(jwks []byte) (KeyProvider, error) {
+	set, err := parseJWKSet(jwks)
+	if err != nil {
+		return nil, err
+	}
+	if len(set) == 0 {
+		return nil, errors.New("JWK Set has no usable keys")
+	}
+	return set, nil
+}
+
+func (set jwkSet) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	jwk, ok := set.lookup(h)
+	if !ok {
+		return nil, fmt.Errorf("Unknown kid %q", h.Kid)
+	}
+	return jwk, nil
+}
+
+// Decode a JWK's public key along with its usage restrictions
+func parseJWKWithUsage(data []byte) (*JWK, error) {
+	key, err := ParseJWK(data)
+	if err != nil {
+		return nil, err
+	}
+	jwk := &JWK{Key: key}
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+	return jwk, nil
+}
+
+// Decode the usage restrictions of an EC JWK on a curve ParseJWK does
+// not support. Returns nil for any other JWK
+func parseUnsupportedCurve(data []byte) *JWK {
+	var params jwkParams
+	if err := json.Unmarshal(data, &params); err != nil || params.Kty != "EC" {
+		return nil
+	}
+	switch params.Crv {
+	case "", "P-256", "P-384", "P-521":
+		return nil
+	}
+	jwk := &JWK{unsupportedCrv: params.Crv}
+	if err := json.Unmarshal(data, jwk); err != nil {
+		return nil
+	}
+	return jwk
+}
+
+// Reject an EC JWK whose "crv" is not the curve alg requires, before
+// attempting to use it. Catches curves this package cannot represent,
+// such as secp256k1, with the same error as a mismatched known curve
+func checkJWKCurve(data []byte, alg Algorithm) error {
+	curve, ok := ecdsaCurves[alg]
+	if !ok {
+		return nil
+	}
+	var params jwkParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+	if params.Kty == "EC" && params.Crv != curve.Params().Name {
+		return fmt.Errorf("%w: %s requires %s, JWK is %s", ErrCurveMismatch, alg, curve.Params().Name, params.Crv)
+	}
+	return nil
+}
+
+// Decode the public key from an RSA, EC or Ed25519 JWK (RFC 7518 6).
+// Private key members are ignored
+func ParseJWK(data []byte) (crypto.PublicKey, error) {
+	var params jwkParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("Failed to decode JWK: %v", err)
+	}
+
+	switch params.Kty {
+	case "RSA":
+		if params.N == "" || params.E == "" {
+			return nil, errors.New("Malformed RSA JWK: missing n or e")
+		}
+		n, err := safeDecode(params.N)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed RSA JWK modulus: %v", err)
+		}
+		e, err := safeDecode(params.E)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed RSA JWK exponent: %v", err)
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("Malformed RSA JWK exponent")
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(exponent.Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var validate ecdh.Curve
+		switch params.Crv {
+		case "P-256":
+			curve, validate = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, validate = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, validate = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("Unsupported EC JWK curve: %q", params.Crv)
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		x, err := safeDecode(params.X)
+		if err != nil || len(x) != size {
+			return nil, errors.New("Malformed EC JWK x coordinate")
+		}
+		y, err := safeDecode(params.Y)
+		if err != nil || len(y) != size {
+			return nil, errors.New("Malformed EC JWK y coordinate")
+		}
+
+		// reject points that aren't on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := validate.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("Invalid EC JWK: %v", err)
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "OKP":
+		if params.Crv != "Ed25519" {
+			return nil, fmt.Errorf("Unsupported OKP JWK curve: %q", params.Crv)
+		}
+		x, err := safeDecode(params.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("Malformed Ed25519 JWK")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("Unsupported JWK key type: %q", params.Kty)
+	}
+}
+
+// Compute the RFC 7638 JWK thumbprint (base64url SHA-256) of an RSA,
+// EC or Ed25519 key. Private keys are thumbprinted by their public half.
+func Thumbprint(key crypto.PublicKey) (string, error) {
+	key, err := publicHalf(key)
+	if err != nil {
+		return "", err
+	}
+
+	// members in lexicographic order, as required for the hash input
+	var members string
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		e := big.NewInt(int64(k.E)).Bytes()
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(e),
+			base64.RawURLEncoding.EncodeToString(k.N.Bytes()))
+
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		x, y := make([]byte, size), make([]byte, size)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		members = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+			k.Curve.Params().Name,
+			base64.RawURLEncoding.EncodeToString(x),
+			base64.RawURLEncoding.EncodeToString(y))
+
+	case ed25519.PublicKey:
+		members = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(k))
+
+	default:
+		return "", fmt.Errorf("Cannot thumbprint key of type %T", key)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
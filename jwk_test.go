@@ -0,0 +1,322 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// build a compact ES256 JWS from a JSON header and payload
+func signES256(t *testing.T, key *ecdsa.PrivateKey, header, payload string) string {
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal("ecdsa.Sign: ", err)
+	}
+
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// public JWK for a P-256 key
+func ecJWK(key *ecdsa.PublicKey) string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":"%s","y":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(x),
+		base64.RawURLEncoding.EncodeToString(y))
+}
+
+// RFC 7638 3.1 - Example JWK Thumbprint Computation
+func TestThumbprint_RFC7638(t *testing.T) {
+	const jwk = `{"kty":"RSA","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw","e":"AQAB","alg":"RS256","kid":"2011-04-29"}`
+
+	key, err := ParseJWK([]byte(jwk))
+	if err != nil {
+		t.Fatal("ParseJWK: ", err)
+	}
+
+	thumbprint, err := Thumbprint(key)
+	if err != nil {
+		t.Fatal("Thumbprint: ", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("Unexpected thumbprint: %s", thumbprint)
+	}
+}
+
+func TestParseJWK_Invalid(t *testing.T) {
+	for _, jwk := range []string{
+		`{"kty":"oct","k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"}`,
+		`{"kty":"RSA","e":"AQAB"}`,
+		`{"kty":"RSA","n":"0vx7a+/g","e":"AQAB"}`,
+		`{"kty":"EC","crv":"P-256","x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}`,
+		`{"kty":"EC","crv":"P-192","x":"AAAA","y":"AAAA"}`,
+		`{"kty":"OKP","crv":"X25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+	} {
+		if _, err := ParseJWK([]byte(jwk)); err == nil {
+			t.Errorf("ParseJWK accepted %s", jwk)
+		}
+	}
+}
+
+func TestKeyProviderFromJWKS(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	var members []string
+	for _, kid := range []string{"k1", "k2"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey: ", err)
+		}
+		keys = append(keys, key)
+		jwk := ecJWK(&key.PublicKey)
+		members = append(members, jwk[:len(jwk)-1]+`,"kid":"`+kid+`"}`)
+	}
+	jwks := `{"keys":[` + strings.Join(members, ",") + `,{"kty":"oct","k":"c2VjcmV0","kid":"k3"}]}`
+
+	kp, err := KeyProviderFromJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal("KeyProviderFromJWKS: ", err)
+	}
+
+	jws := signES256(t, keys[1], `{"alg":"ES256","kid":"k2"}`, `{"iss":"joe"}`)
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	for _, header := range []string{
+		`{"alg":"ES256","kid":"k1"}`,
+		`{"alg":"ES256","kid":"k3"}`,
+		`{"alg":"ES256","kid":"unknown"}`,
+		`{"alg":"ES256"}`,
+	} {
+		jws := signES256(t, keys[1], header, `{"iss":"joe"}`)
+		if _, err := VerifyAndDecode(jws, kp); err == nil {
+			t.Fatalf("Verify succeeded for %s", header)
+		}
+	}
+
+	for _, jwks := range []string{`{"keys":`, `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`} {
+		if _, err := KeyProviderFromJWKS([]byte(jwks)); err == nil {
+			t.Fatalf("KeyProviderFromJWKS accepted %s", jwks)
+		}
+	}
+}
+
+func TestKeyProviderFromJWKS_UnsupportedCurve(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	p256 := ecJWK(&signer.PublicKey)
+	secp256k1 := strings.Replace(p256, `"P-256"`, `"secp256k1"`, 1)
+	jwks := `{"keys":[` + p256[:len(p256)-1] + `,"kid":"p256"},` + secp256k1[:len(secp256k1)-1] + `,"kid":"k1"}]}`
+
+	kp, err := KeyProviderFromJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal("KeyProviderFromJWKS: ", err)
+	}
+	jws := signES256(t, signer, `{"alg":"ES256","kid":"p256"}`, `{"iss":"joe"}`)
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	jws = signES256(t, signer, `{"alg":"ES256","kid":"k1"}`, `{"iss":"joe"}`)
+	if _, err := VerifyAndDecode(jws, kp); !errors.Is(err, ErrCurveMismatch) {
+		t.Fatalf("Expected ErrCurveMismatch, got %v", err)
+	}
+
+	// a set holding only the unsupported key still names it
+	kp, err = KeyProviderFromJWKS([]byte(`{"keys":[` + secp256k1 + `]}`))
+	if err != nil {
+		t.Fatal("KeyProviderFromJWKS: ", err)
+	}
+	jws = signES256(t, signer, `{"alg":"ES256"}`, `{"iss":"joe"}`)
+	if _, err := VerifyAndDecode(jws, kp); !errors.Is(err, ErrCurveMismatch) {
+		t.Fatalf("Expected ErrCurveMismatch, got %v", err)
+	}
+}
+
+func TestVerify_TrustEmbeddedJWK(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	configured, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	jws := signES256(t, signer, `{"alg":"ES256","jwk":`+ecJWK(&signer.PublicKey)+`}`, `{"iss":"self"}`)
+	kp := ProviderFromKey(&configured.PublicKey)
+
+	// embedded keys are ignored by default
+	if _, err := VerifyAndDecode(jws, kp); err == nil {
+		t.Fatal("Verify trusted an embedded key by default")
+	}
+
+	trusted, err := Thumbprint(&signer.PublicKey)
+	if err != nil {
+		t.Fatal("Thumbprint: ", err)
+	}
+	errUntrusted := errors.New("thumbprint not allowed")
+	opts := VerifyOptions{
+		TrustEmbeddedJWK: func(h Header, key crypto.PublicKey) error {
+			thumbprint, err := Thumbprint(key)
+			if err != nil {
+				return err
+			}
+			if thumbprint != trusted {
+				return errUntrusted
+			}
+			return nil
+		},
+	}
+
+	if _, err := VerifyWithOptions(jws, kp, opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// an unapproved key is rejected
+	trusted = "some-other-thumbprint"
+	if _, err := VerifyWithOptions(jws, kp, opts); !errors.Is(err, errUntrusted) {
+		t.Fatalf("Expected untrusted key error, got %v", err)
+	}
+}
+
+func TestVerify_JWKKeyOps(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws := signES256(t, signer, `{"alg":"ES256"}`, `{"iss":"joe"}`)
+	base := ecJWK(&signer.PublicKey)
+
+	tests := []struct {
+		usage string
+		ok    bool
+	}{
+		{``, true},
+		{`"key_ops":["verify"]`, true},
+		{`"use":"sig","alg":"ES256"`, true},
+		{`"key_ops":["encrypt","wrapKey"]`, false},
+		{`"key_ops":["sign"]`, false},
+		{`"key_ops":[]`, false},
+		{`"use":"enc"`, false},
+		{`"alg":"ES384"`, false},
+	}
+	for _, tt := range tests {
+		data := base
+		if tt.usage != "" {
+			data = base[:len(base)-1] + "," + tt.usage + "}"
+		}
+		jwk, err := parseJWKWithUsage([]byte(data))
+		if err != nil {
+			t.Fatal("parseJWKWithUsage: ", err)
+		}
+
+		_, err = VerifyAndDecode(jws, ProviderFromKey(jwk))
+		if tt.ok && err != nil {
+			t.Fatalf("%s: Verify: %v", tt.usage, err)
+		}
+		if !tt.ok && err == nil {
+			t.Fatalf("%s: Verify succeeded with a key not permitted to verify", tt.usage)
+		}
+	}
+}
+
+func TestVerify_CurveMismatch(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	trustAll := VerifyOptions{
+		TrustEmbeddedJWK: func(h Header, key crypto.PublicKey) error { return nil },
+	}
+
+	// secp256k1 coordinates are the same size as P-256 ones
+	secp256k1 := strings.Replace(ecJWK(&signer.PublicKey), `"P-256"`, `"secp256k1"`, 1)
+	jws := signES256(t, signer, `{"alg":"ES256","jwk":`+secp256k1+`}`, `{"iss":"self"}`)
+	if _, err := VerifyWithOptions(jws, ProviderFromKey(NoneKey), trustAll); !errors.Is(err, ErrCurveMismatch) {
+		t.Fatalf("Expected ErrCurveMismatch, got %v", err)
+	}
+
+	// a key on another supported curve is rejected the same way
+	other, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws = signES256(t, signer, `{"alg":"ES256"}`, `{"iss":"joe"}`)
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&other.PublicKey)); !errors.Is(err, ErrCurveMismatch) {
+		t.Fatalf("Expected ErrCurveMismatch, got %v", err)
+	}
+}
+
+func TestVerify_ECDHKey(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws := signES256(t, signer, `{"alg":"ES256"}`, `{"iss":"joe"}`)
+
+	pub, err := signer.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal("ECDH: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(pub)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	priv, err := signer.ECDH()
+	if err != nil {
+		t.Fatal("ECDH: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(priv)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// X25519 has no ECDSA counterpart
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(x25519.PublicKey())); err == nil {
+		t.Fatal("Verify succeeded with an X25519 key")
+	}
+}
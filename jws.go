@@ -26,17 +26,13 @@ package gojws
 
 import (
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/hmac"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"hash"
 	"io"
-	"math/big"
 	"strings"
 )
 
@@ -56,8 +52,31 @@ const (
 	ALG_PS256 = Algorithm("PS256")
 	ALG_PS384 = Algorithm("PS384")
 	ALG_PS512 = Algorithm("PS512")
+	ALG_EDDSA = Algorithm("EdDSA")
 )
 
+// Returned when a token is not structured as a compact JWS
+var ErrMalformedJWS = errors.New("Malformed JWS")
+
+// Returned when a token's protected header has no "alg"
+var ErrMissingAlg = errors.New("JWS header is missing \"alg\"")
+
+// Returned when an unencoded (RFC 7797) payload contains a '.', which
+// the compact serialization cannot represent
+var ErrUnencodedPayloadDot = errors.New("Unencoded JWS payload must not contain '.' in compact serialization")
+
+// Returned when a token's algorithm is not in
+// VerifyOptions.AllowedAlgorithms
+var ErrAlgorithmNotAllowed = errors.New("JWS algorithm not allowed")
+
+// Returned when a token names a key ID reported as revoked
+var ErrKeyRevoked = errors.New("JWS signing key has been revoked")
+
+// Returned by a KeyProvider that has just refreshed its keys, for
+// example after seeing an unknown kid, to have the verifier ask it for
+// the key once more. Only a single retry is made per verification
+var ErrKeyRefreshed = errors.New("JWS keys refreshed, retry lookup")
+
 // Public key to use for "none" algorithm. This type effectively
 // works as a flag allowing no signature verification if none
 // is provided in the JWS
@@ -71,6 +90,33 @@ type KeyProvider interface {
 	GetJWSKey(h Header) (crypto.PublicKey, error)
 }
 
+// Optionally implemented by a KeyProvider that can offer several
+// candidate keys for a header, as during key rotation when a token may
+// be signed with either the previous or the new key. VerifyWithOptions
+// tries each key in turn and accepts the token if any one verifies it.
+type MultiKeyProvider interface {
+	GetJWSKeys(h Header) ([]crypto.PublicKey, error)
+}
+
+// Lifecycle state of a key in a key store
+type KeyStatus int
+
+const (
+	KEY_ACTIVE  = KeyStatus(iota) // used for signing and verification
+	KEY_RETIRED                   // kept only to verify existing tokens
+)
+
+// Optionally implemented by a KeyProvider that tracks key lifecycle,
+// reporting the status of the key GetJWSKey selects for a header. Used
+// by VerifyOptions.ActiveKeysOnly.
+type KeyStatusProvider interface {
+	GetJWSKeyStatus(h Header) (KeyStatus, error)
+}
+
+// Returned when VerifyOptions.ActiveKeysOnly is set and the token was
+// signed by a key that is not active
+var ErrKeyNotActive = errors.New("JWS signing key is not active")
+
 // convert a single key into a provider
 func ProviderFromKey(key crypto.PublicKey) KeyProvider {
 	return singleKey{key: key}
@@ -84,228 +130,692 @@ func (sk singleKey) GetJWSKey(h Header) (crypto.PublicKey, error) {
 	return sk.key, nil
 }
 
+// convert a set of keys, one per algorithm, into a provider that
+// selects by the header's "alg". Because the key is bound to its
+// algorithm, the EdDSA key may be given as raw 32 byte public or 64 byte
+// private key bytes.
+func ProviderFromAlgKeys(keys map[Algorithm]crypto.PublicKey) KeyProvider {
+	return algKeys(keys)
+}
+
+type algKeys map[Algorithm]crypto.PublicKey
+
+func (ak algKeys) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	key, ok := ak[h.Alg]
+	if !ok {
+		return nil, fmt.Errorf("No key registered for algorithm %s", h.Alg)
+	}
+	if raw, ok := key.([]byte); ok && h.Alg == ALG_EDDSA {
+		// never passed on as []byte, which would also be accepted as
+		// an HMAC secret
+		switch len(raw) {
+		case ed25519.PublicKeySize:
+			return ed25519.PublicKey(raw), nil
+		case ed25519.PrivateKeySize:
+			return ed25519.PrivateKey(raw).Public(), nil
+		}
+		return nil, fmt.Errorf("Expected %d or %d byte Ed25519 key. Got %d bytes", ed25519.PublicKeySize, ed25519.PrivateKeySize, len(raw))
+	}
+	return key, nil
+}
+
+// wrap primary so that when it fails with a *KeyFetchError the key is
+// looked up in fallback instead. Any other error from primary, such as
+// an unknown kid, is returned as is.
+func WithFallback(primary, fallback KeyProvider) KeyProvider {
+	return fallbackProvider{primary: primary, fallback: fallback}
+}
+
+type fallbackProvider struct {
+	primary  KeyProvider
+	fallback KeyProvider
+}
+
+func (fp fallbackProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	key, err := fp.primary.GetJWSKey(h)
+	var fetchErr *KeyFetchError
+	if errors.As(err, &fetchErr) {
+		return fp.fallback.GetJWSKey(h)
+	}
+	return key, err
+}
+
 // JWS header
 type Header struct {
-	Alg Algorithm `json:"alg"`
-	Typ string    `json:"typ,omitempty"`
-	Cty string    `json:"cty,omitempty"`
-	Jku string    `json:"jku,omitempty"`
-	Jwk string    `json:"jwk,omitempty"`
-	X5u string    `json:"x5u,omitempty"`
-	X5t string    `json:"x5t,omitempty"`
-	X5c string    `json:"x5c,omitempty"`
-	Kid string    `json:"kid,omitempty"`
+	Alg Algorithm       `json:"alg"`
+	Typ string          `json:"typ,omitempty"`
+	Cty string          `json:"cty,omitempty"`
+	Jku string          `json:"jku,omitempty"`
+	Jwk json.RawMessage `json:"jwk,omitempty"`
+	X5u string          `json:"x5u,omitempty"`
+	X5t string          `json:"x5t,omitempty"`
+	X5c string          `json:"x5c,omitempty"`
+	Kid string          `json:"kid,omitempty"`
+
+	// RFC 7797 unencoded payload option. Only honored when "b64" is
+	// also listed in Crit
+	B64  *bool    `json:"b64,omitempty"`
+	Crit []string `json:"crit,omitempty"`
 }
 
-// Verify the authenticity of a JWS signature
-func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, payload []byte, err error) {
-	parts := strings.Split(jws, ".")
-	if len(parts) != 3 {
-		err = errors.New("Malformed JWS")
+// Is the payload base64url encoded, or carried as-is (RFC 7797)
+func (h Header) payloadEncoded() bool {
+	return h.B64 == nil || *h.B64
+}
+
+// Does the header list the parameter as critical
+func (h Header) isCritical(param string) bool {
+	for _, c := range h.Crit {
+		if c == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Check "crit" and the "b64" extension it governs. Shared by signing
+// and verification so that a signed header always verifies
+func (h Header) checkExtensions() error {
+	if err := h.checkCritical(); err != nil {
+		return err
+	}
+	if !h.payloadEncoded() && !h.isCritical("b64") {
+		return errors.New("Unencoded JWS payload requires \"b64\" in \"crit\"")
+	}
+	return nil
+}
+
+// Check "crit" (RFC 7515 4.1.11). It must be a non-empty list of
+// distinct extensions this package understands, and "b64" is the only
+// one; a JWS marking anything else critical must be rejected
+func (h Header) checkCritical() error {
+	if h.Crit == nil {
+		return nil
+	}
+	if len(h.Crit) == 0 {
+		return errors.New("JWS \"crit\" header parameter must not be empty")
+	}
+	for ii, name := range h.Crit {
+		if name != "b64" {
+			return fmt.Errorf("Unsupported critical JWS header parameter: %s", name)
+		}
+		for _, previous := range h.Crit[:ii] {
+			if previous == name {
+				return fmt.Errorf("Duplicate critical JWS header parameter: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Optional behavior for VerifyWithOptions. The zero value verifies
+// exactly like VerifyAndDecodeWithHeader
+type VerifyOptions struct {
+	// Transform applied to the raw token before anything else, for
+	// example to unwrap a gateway envelope. Its output is what gets
+	// verified
+	PreProcess func(jws string) (string, error)
+
+	// Record the fingerprint of the verifying key in the result
+	KeyFingerprint bool
+
+	// When the header has no "kid", identify the verifying key in the
+	// result by its RFC 7638 thumbprint
+	ThumbprintKid bool
+
+	// When non-nil, only tokens using one of these algorithms are
+	// accepted. Checked before the key provider is consulted
+	AllowedAlgorithms []Algorithm
+
+	// When non-nil, reject tokens whose protected header carries any
+	// parameter not listed here. "alg" is always allowed
+	AllowedHeaderParams []string
+
+	// Reject ECDSA signatures whose S value exceeds half the curve
+	// order, preventing signature malleability
+	RequireLowS bool
+
+	// NON-COMPLIANT and unsafe with untrusted keys: when a token has
+	// no "alg", choose one from the key type instead of rejecting it
+	// (RS256 for RSA, ES256/384/512 by curve, EdDSA, HS256 for []byte).
+	// RFC 7515 requires "alg"; enable only for known producers that
+	// omit it. Tokens that carry "alg" are unaffected
+	InferAlgFromKey bool
+
+	// When positive, reject RSA keys with a modulus larger than this
+	// many bits before doing any work with them. This bounds the cost
+	// of verifying against keys from untrusted sources
+	MaxRSABits int
+
+	// Non-standard domain separation: bytes prepended to the signing
+	// input before verification. The signer must prepend the same
+	// context, as SignOptions.SigningContext does. Empty for standard
+	// JWS
+	SigningContext []byte
+
+	// Compatibility for producers that consistently emit standard
+	// base64: when the token contains '+', '/' or '=' every segment is
+	// decoded with the standard alphabet, padded or not
+	AllowStdBase64 bool
+
+	// Reports whether a key ID has been revoked. Tokens naming a
+	// revoked kid are rejected with ErrKeyRevoked before the key
+	// provider is consulted
+	IsRevokedKid func(kid string) bool
+
+	// Only accept tokens signed by a key the provider reports as
+	// KEY_ACTIVE. The provider must implement KeyStatusProvider, and
+	// embedded "jwk" keys are rejected. Meant for probes checking the
+	// current signing key; normal verification also accepts retired
+	// keys
+	ActiveKeysOnly bool
+
+	// Approves the public key embedded in a token's "jwk" header, for
+	// example by checking its Thumbprint against an allowlist. A token
+	// vouching for its own key is only as trustworthy as this check, so
+	// embedded keys are ignored unless it is set. When set and a token
+	// carries a "jwk", the embedded key is verified with if the callback
+	// returns nil and the token is rejected otherwise
+	TrustEmbeddedJWK func(h Header, key crypto.PublicKey) error
+
+	// Validate the payload's claims once the signature is verified.
+	// nil leaves the payload uninterpreted
+	Claims *ClaimsOptions
+
+	// Stricter checks evaluated, but not enforced, after a token passes
+	// verification, for measuring the impact of tightening validation.
+	// Whatever they would reject is passed to OnFailure with enforced
+	// false while the verification still succeeds
+	ReportOnly *ReportOnlyOptions
+
+	// Called with the error of every failed verification, and of every
+	// would-be rejection under ReportOnly
+	OnFailure func(err error, enforced bool)
+}
+
+// Outcome of a successful verification
+type VerifyResult struct {
+	Header  Header
+	Payload []byte
+
+	// Fingerprint (see KeyFingerprint) of the key that verified the
+	// signature. Only set when requested, and never for symmetric keys
+	KeyFingerprint string
+
+	// Protected header segment exactly as it appeared in the token
+	HeaderSegment string
+
+	// The token expires within ClaimsOptions.NearExpiryWindow
+	NearExpiry bool
+
+	// Identifier of the verifying key: the header "kid" when present,
+	// otherwise the key's RFC 7638 thumbprint if ThumbprintKid is set.
+	// Empty for a symmetric key without a "kid", since its thumbprint
+	// would expose the secret
+	Kid string
+}
+
+// Checks for VerifyOptions.ReportOnly. They are evaluated against the
+// header, payload and key of a token that already passed verification,
+// so the signature is not checked again
+type ReportOnlyOptions struct {
+	// As VerifyOptions.AllowedAlgorithms
+	AllowedAlgorithms []Algorithm
+
+	// As VerifyOptions.IsRevokedKid
+	IsRevokedKid func(kid string) bool
+
+	// As VerifyOptions.MaxRSABits
+	MaxRSABits int
+
+	// As VerifyOptions.Claims
+	Claims *ClaimsOptions
+}
+
+// Report what ro would have rejected about a verified token
+func (ro *ReportOnlyOptions) check(result VerifyResult, key crypto.PublicKey) (err error) {
+	header := result.Header
+	defer wrapVerificationError(&err, header)
+
+	if err = (VerifyOptions{AllowedAlgorithms: ro.AllowedAlgorithms}).algAllowed(header.Alg); err != nil {
 		return
 	}
+	if ro.IsRevokedKid != nil && ro.IsRevokedKid(header.Kid) {
+		err = fmt.Errorf("%w: %s", ErrKeyRevoked, header.Kid)
+		return
+	}
+	if ro.MaxRSABits > 0 {
+		// unwrapped as on the enforced path, so private keys count too
+		switch key.(type) {
+		case *rsa.PublicKey, *rsa.PrivateKey:
+			if _, err = rsaVerificationKey(key, &VerifyOptions{MaxRSABits: ro.MaxRSABits}); err != nil {
+				return
+			}
+		}
+	}
+	if ro.Claims != nil {
+		err = ro.Claims.validate(result.Payload)
+	}
+	return
+}
+
+func (opts VerifyOptions) algAllowed(alg Algorithm) error {
+	if opts.AllowedAlgorithms == nil {
+		return nil
+	}
+	for _, allowed := range opts.AllowedAlgorithms {
+		if allowed == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, alg)
+}
 
-	// decode the JWS header
-	data, err := safeDecode(parts[0])
-	if err != nil {
-		err = fmt.Errorf("Malformed JWS header: %v", err)
+func (opts VerifyOptions) headerParamAllowed(name string) bool {
+	if name == "alg" {
+		return true
+	}
+	for _, allowed := range opts.AllowedHeaderParams {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// write the (optionally context-prefixed) JWS signing input
+func writeSigningInput(w io.Writer, context []byte, header, payload string) {
+	w.Write(context)
+	io.WriteString(w, header)
+	io.WriteString(w, ".")
+	io.WriteString(w, payload)
+}
+
+// Split a compact JWS on its first and last '.' so an unencoded
+// payload that contains a '.' can be reported once the header is known.
+// Returns an array rather than a slice so splitting does not allocate
+// (see BenchmarkSplit_ByteScan)
+func splitCompact(jws string) (parts [3]string, ok bool) {
+	headerEnd := strings.IndexByte(jws, '.')
+	signatureStart := strings.LastIndexByte(jws, '.')
+	if headerEnd == -1 || headerEnd == signatureStart {
 		return
 	}
-	err = json.Unmarshal(data, &header)
-	if err != nil {
-		err = fmt.Errorf("Failed to decode header: %v", err)
+	return [3]string{jws[:headerEnd], jws[headerEnd+1 : signatureStart], jws[signatureStart+1:]}, true
+}
+
+// Split a compact JWS into its three segments without decoding them.
+// The header and signature must be non-empty, and every segment must
+// use only the unpadded base64url alphabet; the payload may be empty
+// for detached content. Fails with ErrMalformedJWS otherwise.
+func SplitToken(jws string) (header, payload, signature string, err error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		err = fmt.Errorf("%w: %d segments", ErrMalformedJWS, len(parts))
+		return
+	}
+	if parts[0] == "" || parts[2] == "" {
+		err = fmt.Errorf("%w: empty segment", ErrMalformedJWS)
 		return
 	}
+	for _, part := range parts {
+		if strings.IndexFunc(part, notBase64URL) != -1 {
+			err = fmt.Errorf("%w: invalid base64url segment", ErrMalformedJWS)
+			return
+		}
+	}
+	return parts[0], parts[1], parts[2], nil
+}
 
-	// acquire the public key
-	key, err := kp.GetJWSKey(header)
+func notBase64URL(r rune) bool {
+	return !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+}
+
+// Look up the verification key for a decoded header
+func acquireKey(kp KeyProvider, header Header, opts *VerifyOptions) (crypto.PublicKey, error) {
+	keys, err := acquireKeys(kp, header, opts, false)
 	if err != nil {
-		err = fmt.Errorf("Failed to acquire public key: %v", err)
-		return
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// Look up the candidate verification keys for a decoded header. Only a
+// MultiKeyProvider offers more than one, and only when multi is set.
+// Candidates whose JWK usage forbids verification are dropped
+func acquireKeys(kp KeyProvider, header Header, opts *VerifyOptions, multi bool) ([]crypto.PublicKey, error) {
+	if opts.IsRevokedKid != nil && opts.IsRevokedKid(header.Kid) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyRevoked, header.Kid)
+	}
+
+	if opts.TrustEmbeddedJWK != nil && len(header.Jwk) > 0 {
+		if err := checkJWKCurve(header.Jwk, header.Alg); err != nil {
+			return nil, err
+		}
+		jwk, err := parseJWKWithUsage(header.Jwk)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed embedded JWK: %v", err)
+		}
+		if err := jwk.permitsVerify(header.Alg); err != nil {
+			return nil, err
+		}
+		if err := opts.TrustEmbeddedJWK(header, jwk.Key); err != nil {
+			return nil, fmt.Errorf("Embedded JWK not trusted: %w", err)
+		}
+		if opts.ActiveKeysOnly {
+			return nil, fmt.Errorf("%w: embedded JWK", ErrKeyNotActive)
+		}
+		return []crypto.PublicKey{jwk.Key}, nil
 	}
 
-	// validate the signature
-	signature, err := safeDecode(parts[2])
+	getKeys := func() ([]crypto.PublicKey, error) {
+		key, err := kp.GetJWSKey(header)
+		return []crypto.PublicKey{key}, err
+	}
+	if mkp, ok := kp.(MultiKeyProvider); ok && multi {
+		getKeys = func() ([]crypto.PublicKey, error) {
+			return mkp.GetJWSKeys(header)
+		}
+	}
+	candidates, err := getKeys()
+	if errors.Is(err, ErrKeyRefreshed) {
+		candidates, err = getKeys()
+	}
+	if err == nil && len(candidates) == 0 {
+		err = errors.New("No candidate keys")
+	}
 	if err != nil {
-		err = fmt.Errorf("Malformed JWS signature: %v", err)
-		return
+		return nil, fmt.Errorf("Failed to acquire public key: %w", err)
 	}
 
-	switch header.Alg {
-	case ALG_NONE:
-		// only allow plaintext if the caller explicitly passed in the
-		// "none" public key
-		if key != NoneKey {
-			err = errors.New("Refusing to validate plaintext JWS")
-			return
+	keys := make([]crypto.PublicKey, 0, len(candidates))
+	for _, key := range candidates {
+		if jwk, ok := key.(*JWK); ok {
+			if err = jwk.permitsVerify(header.Alg); err != nil {
+				continue
+			}
+			key = jwk.Key
 		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, err
+	}
 
-	case ALG_HS256, ALG_HS384, ALG_HS512:
-		symmetricKey, ok := key.([]byte)
+	if opts.ActiveKeysOnly {
+		sp, ok := kp.(KeyStatusProvider)
 		if !ok {
-			err = fmt.Errorf("Expected symmetric ([]byte) key. Got %T", key)
-			return
+			return nil, fmt.Errorf("Key provider %T does not report key status", kp)
 		}
-
-		var hfunc func() hash.Hash
-		if header.Alg == ALG_HS256 {
-			hfunc = sha256.New
-		} else if header.Alg == ALG_HS384 {
-			hfunc = sha512.New384
-		} else if header.Alg == ALG_HS512 {
-			hfunc = sha512.New
-		} else {
-			panic("Algorithm logic error with " + header.Alg)
+		status, err := sp.GetJWSKeyStatus(header)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to acquire key status: %w", err)
+		}
+		if status != KEY_ACTIVE {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotActive, header.Kid)
 		}
+	}
+	return keys, nil
+}
 
-		hm := hmac.New(hfunc, symmetricKey)
-		io.WriteString(hm, parts[0])
-		io.WriteString(hm, ".")
-		io.WriteString(hm, parts[1])
+// Decode and check the base64url protected header segment
+func decodeProtectedHeader(segment string, decode func(string) ([]byte, error), opts *VerifyOptions) (header Header, err error) {
+	data, err := decode(segment)
+	if err != nil {
+		err = fmt.Errorf("%w header: %v", ErrMalformedJWS, err)
+		return
+	}
+	err = json.Unmarshal(data, &header)
+	if err != nil {
+		err = fmt.Errorf("Failed to decode header: %v", err)
+		return
+	}
+	defer wrapVerificationError(&err, header)
 
-		expectedSignature := hm.Sum(nil)
-		if !hmac.Equal(expectedSignature, signature) {
-			err = errors.New("Signature verification failed")
+	if opts.AllowedHeaderParams != nil {
+		var params map[string]json.RawMessage
+		err = json.Unmarshal(data, &params)
+		if err != nil {
+			err = fmt.Errorf("Failed to decode header: %v", err)
 			return
 		}
-
-	case ALG_RS256, ALG_RS384, ALG_RS512:
-		pubKey, ok := key.(*rsa.PublicKey)
-		if !ok {
-			privKey, ok := key.(*rsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected RSA key. Got %T", key)
+		for name := range params {
+			if !opts.headerParamAllowed(name) {
+				err = fmt.Errorf("Unexpected JWS header parameter: %s", name)
 				return
 			}
-			pubKey = &privKey.PublicKey
-		}
-
-		var htype crypto.Hash
-		var hs hash.Hash
-		if header.Alg == ALG_RS256 {
-			hs = sha256.New()
-			htype = crypto.SHA256
-		} else if header.Alg == ALG_RS384 {
-			hs = sha512.New384()
-			htype = crypto.SHA384
-		} else if header.Alg == ALG_RS512 {
-			hs = sha512.New()
-			htype = crypto.SHA512
-		} else {
-			panic("Algorithm logic error with " + header.Alg)
 		}
+	}
 
-		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+	err = header.checkExtensions()
+	return
+}
 
-		err = rsa.VerifyPKCS1v15(pubKey, htype, hs.Sum(nil), signature)
-		if err != nil {
-			err = errors.New("Signature verification failed")
-			return
+// Split a compact JWS and decode its protected header, rejecting a
+// payload segment that contains a '.' and, unless opts.InferAlgFromKey
+// is set, a header without "alg". Segments are not otherwise decoded
+func parseCompact(jws string, decode func(string) ([]byte, error), opts *VerifyOptions) (parts [3]string, header Header, err error) {
+	parts, ok := splitCompact(jws)
+	if !ok {
+		err = ErrMalformedJWS
+		return
+	}
+	header, err = decodeProtectedHeader(parts[0], decode, opts)
+	if err != nil {
+		return
+	}
+	defer wrapVerificationError(&err, header)
+
+	if strings.Contains(parts[1], ".") {
+		if header.payloadEncoded() {
+			err = ErrMalformedJWS
+		} else {
+			err = ErrUnencodedPayloadDot
 		}
+		return
+	}
+	if header.Alg == "" && !opts.InferAlgFromKey {
+		err = ErrMissingAlg
+		return
+	}
+	return
+}
 
-	case ALG_ES256, ALG_ES384, ALG_ES512:
-		pubKey, ok := key.(*ecdsa.PublicKey)
-		if !ok {
-			privKey, ok := key.(*ecdsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected ECDSA key. Got %T", key)
-				return
+// Verify the authenticity of a JWS signature
+func VerifyAndDecodeWithHeader(jws string, kp KeyProvider) (header Header, payload []byte, err error) {
+	result, err := VerifyWithOptions(jws, kp, VerifyOptions{})
+	return result.Header, result.Payload, err
+}
+
+// Verify the authenticity of a JWS signature, returning the protected
+// header as a generic map that includes parameters Header does not
+// model. The key provider still receives the typed Header
+func VerifyAndDecodeDynamic(jws string, kp KeyProvider) (header map[string]interface{}, payload []byte, err error) {
+	result, err := VerifyWithOptions(jws, kp, VerifyOptions{})
+	if err != nil {
+		return
+	}
+
+	data, err := safeDecode(result.HeaderSegment)
+	if err != nil {
+		err = fmt.Errorf("%w header: %v", ErrMalformedJWS, err)
+		return
+	}
+	if err = json.Unmarshal(data, &header); err != nil {
+		err = fmt.Errorf("Failed to decode header: %v", err)
+		return
+	}
+	return header, result.Payload, nil
+}
+
+// Verify the authenticity of a JWS signature with additional options
+func VerifyWithOptions(jws string, kp KeyProvider, opts VerifyOptions) (result VerifyResult, err error) {
+	if opts.OnFailure != nil {
+		defer func() {
+			if err != nil {
+				opts.OnFailure(err, true)
 			}
+		}()
+	}
+
+	result, _, key, err := verifyToken(jws, kp, &opts, true)
+	if err != nil {
+		return
+	}
+	defer wrapVerificationError(&err, result.Header)
 
-			pubKey = &privKey.PublicKey
+	if opts.Claims != nil {
+		err = opts.Claims.validate(result.Payload)
+		if err == nil {
+			result.NearExpiry = opts.Claims.nearExpiry(result.Payload)
 		}
+	}
 
-		var hs hash.Hash
-		var rSize, sSize int
-		if header.Alg == ALG_ES256 {
-			rSize, sSize = 32, 32
-			hs = sha256.New()
-		} else if header.Alg == ALG_ES384 {
-			rSize, sSize = 48, 48
-			hs = sha512.New384()
-		} else if header.Alg == ALG_ES512 {
-			rSize, sSize = 66, 66
-			hs = sha512.New()
-		} else {
-			panic("Alorithm logic error with " + header.Alg)
+	if err == nil && opts.ReportOnly != nil && opts.OnFailure != nil {
+		if strictErr := opts.ReportOnly.check(result, key); strictErr != nil {
+			opts.OnFailure(strictErr, false)
 		}
+	}
+	return
+}
 
-		// split signature into R and S
-		if len(signature) != rSize+sSize {
-			err = errors.New("Signature verification failed")
+// Verify the signature of a compact JWS, leaving claims to the caller.
+// The payload is decoded into result only when decodePayload is set;
+// otherwise its segment is returned with only the alphabet checked
+func verifyToken(jws string, kp KeyProvider, opts *VerifyOptions, decodePayload bool) (result VerifyResult, payloadSegment string, key crypto.PublicKey, err error) {
+	if opts.PreProcess != nil {
+		jws, err = opts.PreProcess(jws)
+		if err != nil {
+			err = fmt.Errorf("Failed to preprocess JWS: %w", err)
 			return
 		}
+	}
 
-		r, s := new(big.Int), new(big.Int)
-		r.SetBytes(signature[:rSize])
-		s.SetBytes(signature[rSize:])
+	decode := safeDecode
+	if opts.AllowStdBase64 && strings.ContainsAny(jws, "+/=") {
+		decode = stdDecode
+	}
 
-		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+	// split the JWS and decode its header
+	parts, header, err := parseCompact(jws, decode, opts)
+	if err != nil {
+		return
+	}
+	result.Header = header
+	result.HeaderSegment = parts[0]
+	defer wrapVerificationError(&err, header)
+
+	// decode the payload up front so a malformed segment is reported
+	// as such rather than as a signature failure. It is only exposed
+	// once the signature is verified
+	var payload []byte
+	if !header.payloadEncoded() {
+		if decodePayload {
+			payload = []byte(parts[1])
+		}
+	} else if decodePayload {
+		payload, err = decode(parts[1])
+		if err != nil {
+			err = fmt.Errorf("%w payload: %v", ErrMalformedJWS, err)
+			return
+		}
+	} else if strings.IndexFunc(parts[1], notBase64URL) != -1 {
+		// a streaming decoder skips newlines, so check the alphabet
+		// before reading
+		err = fmt.Errorf("%w payload: invalid base64url", ErrMalformedJWS)
+		return
+	}
 
-		if !ecdsa.Verify(pubKey, hs.Sum(nil), r, s) {
-			err = errors.New("Signature verification failed")
+	if header.Alg != "" {
+		if err = opts.algAllowed(header.Alg); err != nil {
 			return
 		}
+	}
 
-	case ALG_PS256, ALG_PS384, ALG_PS512:
-		pubKey, ok := key.(*rsa.PublicKey)
-		if !ok {
-			privKey, ok := key.(*rsa.PrivateKey)
-			if !ok {
-				err = fmt.Errorf("Expected RSA key. Got %T", key)
-				return
-			}
+	// acquire the candidate public keys
+	keys, err := acquireKeys(kp, header, opts, true)
+	if err != nil {
+		return
+	}
 
-			pubKey = &privKey.PublicKey
-		}
+	signature, err := decode(parts[2])
+	if err != nil {
+		err = fmt.Errorf("%w signature: %v", ErrMalformedJWS, err)
+		return
+	}
 
-		var hs hash.Hash
-		var htype crypto.Hash
-		if header.Alg == ALG_PS256 {
-			hs = sha256.New()
-			htype = crypto.SHA256
-		} else if header.Alg == ALG_PS384 {
-			hs = sha512.New384()
-			htype = crypto.SHA384
-		} else if header.Alg == ALG_PS512 {
-			hs = sha512.New()
-			htype = crypto.SHA512
+	// validate the signature against each candidate until one verifies
+	var failures []error
+	for _, candidate := range keys {
+		if verr := verifyCompact(parts, signature, header.Alg, candidate, opts); verr != nil {
+			failures = append(failures, verr)
+			continue
+		}
+		key = candidate
+		break
+	}
+	if len(failures) == len(keys) {
+		if len(failures) == 1 {
+			err = failures[0]
 		} else {
-			panic("Algorithm logic error with " + header.Alg)
+			err = errors.Join(failures...)
 		}
+		return
+	}
 
-		// generate hashed input
-		io.WriteString(hs, parts[0])
-		io.WriteString(hs, ".")
-		io.WriteString(hs, parts[1])
+	if opts.KeyFingerprint {
+		// symmetric keys are secret, so they are never fingerprinted
+		if fp, fperr := KeyFingerprint(key); fperr == nil {
+			result.KeyFingerprint = fp
+		}
+	}
+
+	result.Kid = header.Kid
+	if result.Kid == "" && opts.ThumbprintKid {
+		result.Kid, _ = Thumbprint(key)
+	}
 
-		err = rsa.VerifyPSS(pubKey, htype, hs.Sum(nil), signature, nil)
+	result.Payload = payload
+	return result, parts[1], key, nil
+}
+
+// Check the signature of a split compact JWS with key, inferring the
+// algorithm from the key when the header has none
+func verifyCompact(parts [3]string, signature []byte, alg Algorithm, key crypto.PublicKey, opts *VerifyOptions) error {
+	if alg == "" {
+		var err error
+		alg, err = inferAlgFromKey(key)
 		if err != nil {
-			err = errors.New("Signature verification failed")
-			return
+			return err
+		}
+		if err = opts.algAllowed(alg); err != nil {
+			return err
 		}
-
-	default:
-		err = fmt.Errorf("Unknown signature algorithm: %s", header.Alg)
-		return
 	}
 
-	// decode the payload
-	payload, err = safeDecode(parts[1])
+	sv, err := newSignatureVerifier(alg, key, opts)
 	if err != nil {
-		err = fmt.Errorf("Malformed JWS payload: %v", err)
-		return
+		return err
 	}
-	return
+	writeSigningInput(sv, opts.SigningContext, parts[0], parts[1])
+	return sv.verify(signature)
 }
 
 func VerifyAndDecode(jws string, kp KeyProvider) (payload []byte, err error) {
 	_, payload, err = VerifyAndDecodeWithHeader(jws, kp)
 	return
 }
+
+// Verify the JWS and also require that the SHA-256 of its decoded
+// payload matches expectedSHA256, binding the token to known content
+func VerifyWithPayloadHash(jws string, kp KeyProvider, expectedSHA256 [32]byte) ([]byte, error) {
+	payload, err := VerifyAndDecode(jws, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	if sha256.Sum256(payload) != expectedSHA256 {
+		return nil, errors.New("JWS payload does not match the expected hash")
+	}
+	return payload, nil
+}
@@ -28,6 +28,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/binary"
@@ -105,6 +106,18 @@ func keyFromJWK(jwk string) (crypto.PrivateKey, error) {
 
 		return data, nil
 
+	case "OKP":
+		if key.Crv != "Ed25519" || key.D == "" {
+			return nil, errors.New("Malformed JWS OKP key")
+		}
+
+		seed, err := safeDecode(key.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("Malformed JWS OKP key")
+		}
+
+		return ed25519.NewKeyFromSeed(seed), nil
+
 	case "EC":
 		if key.Crv == "" || key.X == "" || key.Y == "" || key.D == "" {
 			return nil, errors.New("Malformed JWS EC key")
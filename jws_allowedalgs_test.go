@@ -0,0 +1,77 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+func TestVerify_AllowedAlgorithms(t *testing.T) {
+	privKey, err := keyFromJWK(testRSAKey)
+	if err != nil {
+		t.Fatal("keyFromJWK: ", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privKey.(*rsa.PrivateKey).PublicKey)
+	if err != nil {
+		t.Fatal("MarshalPKIXPublicKey: ", err)
+	}
+	// a careless provider handing out the RSA public key as PEM bytes
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	lookups := 0
+	kp := keyProviderFunc(func(h Header) (crypto.PublicKey, error) {
+		lookups++
+		return publicPEM, nil
+	})
+
+	// the attacker signs with the public key as an HMAC secret
+	forged := signHS256(`{"alg":"HS256"}`, `{"sub":"admin"}`, publicPEM)
+	if _, err := VerifyWithOptions(forged, kp, VerifyOptions{}); err != nil {
+		t.Fatal("Test token must verify without an allowlist: ", err)
+	}
+
+	opts := VerifyOptions{AllowedAlgorithms: []Algorithm{ALG_RS256}}
+	lookups = 0
+	for _, header := range []string{`{"alg":"HS256"}`, `{"alg":"none"}`, `{}`, `{"alg":""}`} {
+		jws := signHS256(header, `{"sub":"admin"}`, publicPEM)
+		if _, err := VerifyWithOptions(jws, kp, opts); err == nil {
+			t.Fatalf("Verify succeeded for %s", header)
+		}
+	}
+	if _, err := VerifyWithOptions(forged, kp, opts); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("Expected ErrAlgorithmNotAllowed, got %v", err)
+	}
+	if lookups != 0 {
+		t.Fatalf("Key looked up %d times for rejected algorithms", lookups)
+	}
+
+	if _, err := VerifyWithOptions(testRS256Token, ProviderFromKey(privKey), opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
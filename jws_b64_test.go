@@ -0,0 +1,100 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// RFC 7797 4.2 - Example with unencoded payload. The "$.02" payload
+// can only be carried detached or in the JSON serialization
+func TestVerify_Unencoded_PeriodRejected(t *testing.T) {
+	const jws = `eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19.$.02.A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY`
+	key := []byte{3, 35, 53, 75, 43, 15, 165, 188, 131, 126, 6, 101, 119, 123, 166,
+		143, 90, 179, 40, 230, 240, 84, 201, 40, 169, 15, 132, 178, 210, 80,
+		46, 191, 211, 251, 90, 146, 210, 6, 71, 239, 150, 138, 180, 195, 119,
+		98, 61, 34, 61, 46, 33, 114, 5, 46, 79, 8, 192, 205, 154, 245, 103,
+		208, 128, 163}
+
+	_, err := VerifyAndDecode(jws, ProviderFromKey(key))
+	if err == nil {
+		t.Fatal("Verify succeeded for an unencoded payload containing '.'")
+	}
+	if !strings.Contains(err.Error(), "must not contain '.'") {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestVerify_Unencoded(t *testing.T) {
+	const jws = `eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19.$02.uB970NMwI0DGAK72LfbzudKpWHiz3tNXh6BzgYICrPA`
+	key := []byte{3, 35, 53, 75, 43, 15, 165, 188, 131, 126, 6, 101, 119, 123, 166,
+		143, 90, 179, 40, 230, 240, 84, 201, 40, 169, 15, 132, 178, 210, 80,
+		46, 191, 211, 251, 90, 146, 210, 6, 71, 239, 150, 138, 180, 195, 119,
+		98, 61, 34, 61, 46, 33, 114, 5, 46, 79, 8, 192, 205, 154, 245, 103,
+		208, 128, 163}
+
+	data, err := VerifyAndDecode(jws, ProviderFromKey(key))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	if !bytes.Equal(data, []byte("$02")) {
+		t.Fatalf("Unexpected payload: %v", data)
+	}
+}
+
+func TestVerify_Unencoded_RequiresCrit(t *testing.T) {
+	// {"alg":"none","b64":false}
+	const jws = `eyJhbGciOiJub25lIiwiYjY0IjpmYWxzZX0.$02.`
+
+	_, err := VerifyAndDecode(jws, ProviderFromKey(NoneKey))
+	if err == nil {
+		t.Fatal("Verify succeeded without \"b64\" in \"crit\"")
+	}
+}
+
+func TestVerify_Crit(t *testing.T) {
+	kp := ProviderFromKey(testHMACKey)
+	jws := signHS256(`{"alg":"HS256","b64":true,"crit":["b64"]}`, `{"iss":"joe"}`, testHMACKey)
+	if _, err := VerifyAndDecode(jws, kp); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	tests := map[string]string{
+		`{"alg":"HS256","crit":["x-must-understand"]}`:      "Unsupported critical",
+		`{"alg":"HS256","b64":true,"crit":["b64","x-ext"]}`: "Unsupported critical",
+		`{"alg":"HS256","b64":true,"crit":["b64","b64"]}`:   "Duplicate critical",
+		`{"alg":"HS256","crit":[]}`:                         "must not be empty",
+	}
+	for header, expected := range tests {
+		jws := signHS256(header, `{"iss":"joe"}`, testHMACKey)
+		_, err := VerifyAndDecode(jws, kp)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%s: expected %q error, got %v", header, expected, err)
+		}
+	}
+}
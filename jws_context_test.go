@@ -0,0 +1,85 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerify_SigningContext(t *testing.T) {
+	context := []byte("example.com/session-upgrade\x00")
+	payload := []byte(`{"iss":"joe"}`)
+
+	for _, tt := range signingKeys(t) {
+		if tt.alg == ALG_NONE {
+			continue
+		}
+		kp := ProviderFromKey(tt.verify)
+
+		jws, err := SignWithOptions(payload, tt.alg, tt.key, Header{}, SignOptions{SigningContext: context})
+		if err != nil {
+			t.Fatalf("%s: SignWithOptions: %v", tt.alg, err)
+		}
+		result, err := VerifyWithOptions(jws, kp, VerifyOptions{SigningContext: context})
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", tt.alg, err)
+		}
+		if !bytes.Equal(result.Payload, payload) {
+			t.Fatalf("%s: Unexpected payload: %s", tt.alg, result.Payload)
+		}
+
+		// a context-bound token must not verify as a standard JWS, or
+		// under a different context
+		if _, err := VerifyAndDecode(jws, kp); err == nil {
+			t.Fatalf("%s: Verify succeeded without the signing context", tt.alg)
+		}
+		if _, err := VerifyWithOptions(jws, kp, VerifyOptions{SigningContext: []byte("other")}); err == nil {
+			t.Fatalf("%s: Verify succeeded with the wrong signing context", tt.alg)
+		}
+	}
+}
+
+func TestVerify_SigningContext_Standard(t *testing.T) {
+	kp := ProviderFromKey(testHMACKey)
+
+	// standard tokens (empty context) verify with and without the option
+	jws, err := SignWithOptions([]byte(`{"iss":"joe"}`), ALG_HS256, testHMACKey, Header{}, SignOptions{SigningContext: []byte{}})
+	if err != nil {
+		t.Fatal("SignWithOptions: ", err)
+	}
+	if jws != signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey) {
+		t.Fatalf("Empty signing context changed the token: %s", jws)
+	}
+	if _, err := VerifyWithOptions(jws, kp, VerifyOptions{}); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, err := VerifyWithOptions(jws, kp, VerifyOptions{SigningContext: []byte{}}); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if _, err := VerifyWithOptions(jws, kp, VerifyOptions{SigningContext: []byte("ctx")}); err == nil {
+		t.Fatal("Verify succeeded for a standard token under a signing context")
+	}
+}
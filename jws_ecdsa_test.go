@@ -0,0 +1,101 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify_ES384(t *testing.T) {
+	// produced by Node.js, see jws_interop_test.go
+	for _, tt := range interopTokens {
+		if tt.alg != ALG_ES384 {
+			continue
+		}
+		key, err := ParseJWK([]byte(tt.key))
+		if err != nil {
+			t.Fatal("ParseJWK: ", err)
+		}
+		if _, err := VerifyAndDecode(tt.jws, ProviderFromKey(key)); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+
+	signer, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws, err := SignAndEncode([]byte(`{"iss":"joe"}`), ALG_ES384, signer, Header{})
+	if err != nil {
+		t.Fatal("SignAndEncode: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(&signer.PublicKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestVerify_ECDSASignatureLength(t *testing.T) {
+	tests := []struct {
+		alg   Algorithm
+		curve elliptic.Curve
+		size  int
+	}{
+		{ALG_ES256, elliptic.P256(), 64},
+		{ALG_ES384, elliptic.P384(), 96},
+		{ALG_ES512, elliptic.P521(), 132},
+	}
+	for _, tt := range tests {
+		signer, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+		if err != nil {
+			t.Fatal("GenerateKey: ", err)
+		}
+		jws, err := SignAndEncode([]byte(`{"iss":"joe"}`), tt.alg, signer, Header{})
+		if err != nil {
+			t.Fatal("SignAndEncode: ", err)
+		}
+		dot := strings.LastIndexByte(jws, '.')
+		signature, err := base64.RawURLEncoding.DecodeString(jws[dot+1:])
+		if err != nil {
+			t.Fatal("DecodeString: ", err)
+		}
+		if len(signature) != tt.size {
+			t.Fatalf("%s: unexpected signature length %d", tt.alg, len(signature))
+		}
+
+		kp := ProviderFromKey(&signer.PublicKey)
+		for _, sig := range [][]byte{signature[:tt.size-1], append(signature, 0), signature[:tt.size/2]} {
+			forged := jws[:dot+1] + base64.RawURLEncoding.EncodeToString(sig)
+			if _, err := VerifyAndDecode(forged, kp); !errors.Is(err, ErrVerificationFailed) {
+				t.Fatalf("%s: expected ErrVerificationFailed for %d bytes, got %v", tt.alg, len(sig), err)
+			}
+		}
+	}
+}
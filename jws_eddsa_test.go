@@ -0,0 +1,143 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+// RFC 8037 A.4 - Ed25519 signing
+func TestVerify_EdDSA_RFC8037(t *testing.T) {
+	const jws = `eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg`
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if err != nil {
+		t.Fatal("DecodeString: ", err)
+	}
+
+	// raw 32-byte public key
+	data, err := VerifyAndDecode(jws, ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: x}))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if !bytes.Equal(data, []byte("Example of Ed25519 signing")) {
+		t.Fatalf("Unexpected payload: %s", data)
+	}
+
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(ed25519.PublicKey(x))); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestVerify_EdDSA_KeyForms(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"joe"}`))
+	jws := signingInput + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(priv, []byte(signingInput)))
+
+	for _, key := range []interface{}{pub, priv} {
+		if _, err := VerifyAndDecode(jws, ProviderFromKey(key)); err != nil {
+			t.Fatalf("Verify with %T: %v", key, err)
+		}
+	}
+
+	// raw bytes are only an Ed25519 key when bound to EdDSA
+	for _, key := range [][]byte{pub, priv} {
+		if _, err := VerifyAndDecode(jws, ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: key})); err != nil {
+			t.Fatalf("Verify with %d raw bytes: %v", len(key), err)
+		}
+		if _, err := VerifyAndDecode(jws, ProviderFromKey(key)); err == nil {
+			t.Fatalf("Verify succeeded with %d unbound raw bytes", len(key))
+		}
+	}
+
+	if _, err := VerifyAndDecode(jws, ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: make([]byte, 48)})); err == nil {
+		t.Fatal("Verify succeeded with a raw key of invalid length")
+	}
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	if _, err := VerifyAndDecode(jws, ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: []byte(other)})); err == nil {
+		t.Fatal("Verify succeeded with the wrong key")
+	}
+}
+
+func TestVerify_EdDSA_HMACForgery(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	// an attacker who knows the public key uses it as an HS256 secret
+	forged := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, []byte(pub))
+	for _, kp := range []KeyProvider{
+		ProviderFromKey(pub),
+		ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: []byte(pub)}),
+		ProviderFromAlgKeys(map[Algorithm]crypto.PublicKey{ALG_EDDSA: pub}),
+	} {
+		if _, err := VerifyAndDecode(forged, kp); err == nil {
+			t.Fatalf("Verify accepted an HS256 token keyed with the Ed25519 public key via %T", kp)
+		}
+	}
+}
+
+func TestVerify_EdDSA_ShortPrivateKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws, err := SignAndEncode([]byte(`{"iss":"joe"}`), ALG_EDDSA, priv, Header{})
+	if err != nil {
+		t.Fatal("SignAndEncode: ", err)
+	}
+
+	// truncated keys are rejected rather than panicking in Public
+	short := priv[:10]
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(short)); err == nil {
+		t.Fatal("Verify succeeded with a truncated private key")
+	}
+	if _, err := VerifyWithOptions(jws, ProviderFromKey(short), VerifyOptions{KeyFingerprint: true, ThumbprintKid: true}); err == nil {
+		t.Fatal("Verify succeeded with a truncated private key")
+	}
+	if _, err := Thumbprint(short); err == nil {
+		t.Fatal("Thumbprint accepted a truncated private key")
+	}
+	if _, err := KeyFingerprint(short); err == nil {
+		t.Fatal("KeyFingerprint accepted a truncated private key")
+	}
+	if _, err := SignAndEncode([]byte(`{"iss":"joe"}`), ALG_EDDSA, short, Header{}); err == nil {
+		t.Fatal("SignAndEncode accepted a truncated private key")
+	}
+}
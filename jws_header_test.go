@@ -25,6 +25,9 @@
 package gojws
 
 import (
+	"crypto"
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -46,3 +49,98 @@ func TestVerify8_HMAC_SHA26_Header(t *testing.T) {
 		t.Fatal("Header decoded incorrectly")
 	}
 }
+
+func TestHeader_JSONTags(t *testing.T) {
+	const data = `{"alg":"ES256","typ":"JWT","cty":"example+json","jwk":{"kty":"EC","crv":"P-256"}}`
+
+	var header Header
+	if err := json.Unmarshal([]byte(data), &header); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	if header.Typ != "JWT" || header.Cty != "example+json" {
+		t.Fatalf("Unexpected typ/cty: %q %q", header.Typ, header.Cty)
+	}
+	if string(header.Jwk) != `{"kty":"EC","crv":"P-256"}` {
+		t.Fatalf("Unexpected jwk: %s", header.Jwk)
+	}
+
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(encoded) != data {
+		t.Fatalf("Header did not round-trip: %s", encoded)
+	}
+}
+
+func TestVerifyAndDecodeWithHeader(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256","typ":"JWT","cty":"example+json","kid":"2024-05"}`, `{"iss":"joe"}`, testHMACKey)
+
+	header, payload, err := VerifyAndDecodeWithHeader(jws, ProviderFromKey(testHMACKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	expected := Header{Alg: ALG_HS256, Typ: "JWT", Cty: "example+json", Kid: "2024-05"}
+	if header.Alg != expected.Alg || header.Typ != expected.Typ || header.Cty != expected.Cty || header.Kid != expected.Kid {
+		t.Fatalf("Unexpected header: %+v", header)
+	}
+	if string(payload) != `{"iss":"joe"}` {
+		t.Fatalf("Unexpected payload: %s", payload)
+	}
+
+	// the header is covered by the signature, so it cannot be swapped
+	forged := signHS256(`{"alg":"HS256","kid":"other"}`, `{"iss":"joe"}`, testHMACKey)
+	tampered := strings.SplitN(forged, ".", 2)[0] + jws[strings.Index(jws, "."):]
+	if _, _, err := VerifyAndDecodeWithHeader(tampered, ProviderFromKey(testHMACKey)); err == nil {
+		t.Fatal("Verify succeeded with a substituted header")
+	}
+}
+
+func TestVerifyResult_HeaderSegment(t *testing.T) {
+	// non-canonical header JSON, whitespace included, must be preserved
+	jws := signHS256("{\"typ\":\"JWT\",\r\n \"alg\":\"HS256\"}", `{"iss":"joe"}`, testHMACKey)
+
+	result, err := VerifyWithOptions(jws, ProviderFromKey(testHMACKey), VerifyOptions{})
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if want := strings.SplitN(jws, ".", 2)[0]; result.HeaderSegment != want {
+		t.Fatalf("Unexpected header segment: %s", result.HeaderSegment)
+	}
+}
+
+func TestVerifyAndDecodeDynamic(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256","kid":"k1","x-tenant":"acme","x-hops":[1,2]}`, `{"iss":"joe"}`, testHMACKey)
+
+	var seen Header
+	kp := keyProviderFunc(func(h Header) (crypto.PublicKey, error) {
+		seen = h
+		return testHMACKey, nil
+	})
+	header, payload, err := VerifyAndDecodeDynamic(jws, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if seen.Kid != "k1" {
+		t.Fatalf("Unexpected typed header: %+v", seen)
+	}
+	if header["alg"] != "HS256" || header["x-tenant"] != "acme" {
+		t.Fatalf("Unexpected header: %v", header)
+	}
+	if hops, ok := header["x-hops"].([]interface{}); !ok || len(hops) != 2 {
+		t.Fatalf("Unexpected x-hops: %v", header["x-hops"])
+	}
+	if string(payload) != `{"iss":"joe"}` {
+		t.Fatalf("Unexpected payload: %s", payload)
+	}
+
+	if _, _, err := VerifyAndDecodeDynamic(jws, ProviderFromKey([]byte("wrong"))); err == nil {
+		t.Fatal("Verify succeeded with the wrong key")
+	}
+}
+
+type keyProviderFunc func(h Header) (crypto.PublicKey, error)
+
+func (f keyProviderFunc) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	return f(h)
+}
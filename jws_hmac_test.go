@@ -0,0 +1,65 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// Wrong-length and wrong-value HMAC signatures must be indistinguishable
+// to the caller; only the length, which is public, can differ in timing
+func TestVerify_HMACFailuresIndistinguishable(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	signingInput := jws[:strings.LastIndexByte(jws, '.')]
+	signature, err := base64.RawURLEncoding.DecodeString(jws[len(signingInput)+1:])
+	if err != nil {
+		t.Fatal("DecodeString: ", err)
+	}
+
+	wrongValue := append([]byte{}, signature...)
+	wrongValue[len(wrongValue)-1] ^= 1
+	forged := map[string][]byte{
+		"wrong value": wrongValue,
+		"truncated":   signature[:16],
+		"extended":    append(append([]byte{}, signature...), 0),
+		"empty":       {},
+	}
+
+	var expected string
+	for name, sig := range forged {
+		_, err := VerifyAndDecode(signingInput+"."+base64.RawURLEncoding.EncodeToString(sig), ProviderFromKey(testHMACKey))
+		if err == nil {
+			t.Fatalf("%s: Verify succeeded with a forged signature", name)
+		}
+		if expected == "" {
+			expected = err.Error()
+		}
+		if err.Error() != expected {
+			t.Fatalf("%s: distinguishable failure %q, expected %q", name, err, expected)
+		}
+	}
+}
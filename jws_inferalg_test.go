@@ -0,0 +1,63 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestVerify_InferAlgFromKey(t *testing.T) {
+	hs256 := signHS256(`{"typ":"JWT"}`, `{"iss":"joe"}`, testHMACKey)
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	es256 := signES256(t, signer, `{"typ":"JWT"}`, `{"iss":"joe"}`)
+
+	tests := []struct {
+		jws string
+		kp  KeyProvider
+	}{
+		{hs256, ProviderFromKey(testHMACKey)},
+		{es256, ProviderFromKey(&signer.PublicKey)},
+	}
+	for _, tt := range tests {
+		if _, err := VerifyAndDecode(tt.jws, tt.kp); err == nil {
+			t.Fatal("Verify accepted a token without alg by default")
+		}
+		if _, err := VerifyWithOptions(tt.jws, tt.kp, VerifyOptions{InferAlgFromKey: true}); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+
+	// an inferred algorithm still has to match the signature
+	if _, err := VerifyWithOptions(hs256, ProviderFromKey(&signer.PublicKey), VerifyOptions{InferAlgFromKey: true}); err == nil {
+		t.Fatal("Verify succeeded with the wrong key type")
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// reader producing n bytes of a repeating pattern, optionally with a
+// single byte altered at offset flip
+type patternReader struct {
+	n, off, flip int
+}
+
+func (pr *patternReader) Read(p []byte) (int, error) {
+	if pr.off >= pr.n {
+		return 0, io.EOF
+	}
+	if len(p) > pr.n-pr.off {
+		p = p[:pr.n-pr.off]
+	}
+	for ii := range p {
+		p[ii] = byte('a' + (pr.off+ii)%26)
+		if pr.off+ii == pr.flip {
+			p[ii] = '!'
+		}
+	}
+	pr.off += len(p)
+	return len(p), nil
+}
+
+// detached flattened JSON HS256 envelope for the payload read from r
+func signDetachedJSON(t *testing.T, header string, r io.Reader, key []byte) []byte {
+	protected := base64.RawURLEncoding.EncodeToString([]byte(header))
+
+	hm := hmac.New(sha256.New, key)
+	io.WriteString(hm, protected+".")
+	encoder := base64.NewEncoder(base64.RawURLEncoding, hm)
+	if _, err := io.Copy(encoder, r); err != nil {
+		t.Fatal("Copy: ", err)
+	}
+	encoder.Close()
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"protected": protected,
+		"header":    map[string]string{"kid": "stream"},
+		"signature": base64.RawURLEncoding.EncodeToString(hm.Sum(nil)),
+	})
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	return envelope
+}
+
+func TestVerifyDetachedJSONReader(t *testing.T) {
+	const size = 16 << 20
+	envelope := signDetachedJSON(t, `{"alg":"HS256"}`, &patternReader{n: size, flip: -1}, testHMACKey)
+
+	header, err := VerifyDetachedJSONReader(envelope, &patternReader{n: size, flip: -1}, ProviderFromKey(testHMACKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if header.Alg != ALG_HS256 || header.Kid != "stream" {
+		t.Fatalf("Unexpected header: %+v", header)
+	}
+
+	_, err = VerifyDetachedJSONReader(envelope, &patternReader{n: size, flip: size / 2}, ProviderFromKey(testHMACKey))
+	if err == nil {
+		t.Fatal("Verify succeeded with a tampered payload")
+	}
+
+	_, err = VerifyDetachedJSONReader(envelope, &patternReader{n: size - 1, flip: -1}, ProviderFromKey(testHMACKey))
+	if err == nil {
+		t.Fatal("Verify succeeded with a truncated payload")
+	}
+}
+
+func TestVerifyDetachedJSONReader_Unencoded(t *testing.T) {
+	// RFC 7797 4.2 - the detached "$.02" example in the JSON serialization
+	envelope := []byte(`{"protected":"eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19","signature":"A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY"}`)
+
+	_, err := VerifyDetachedJSONReader(envelope, bytes.NewReader([]byte("$.02")), ProviderFromKey(testHMACKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestVerifyDetachedJSONReader_AttachedPayload(t *testing.T) {
+	// correctly signed over the detached payload, but also carrying one
+	envelope := signDetachedJSON(t, `{"alg":"HS256"}`, bytes.NewReader(nil), testHMACKey)
+	var members map[string]interface{}
+	if err := json.Unmarshal(envelope, &members); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+	members["payload"] = "eyJpc3MiOiJqb2UifQ"
+	envelope, err := json.Marshal(members)
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+
+	_, err = VerifyDetachedJSONReader(envelope, bytes.NewReader(nil), ProviderFromKey(testHMACKey))
+	if err == nil || err.Error() != "Detached JWS must not carry a payload" {
+		t.Fatalf("Verify succeeded or failed for another reason with an attached payload: %v", err)
+	}
+}
+
+func TestVerifyDetachedJSONReader_DuplicateHeader(t *testing.T) {
+	// correctly signed, with "kid" in both the protected and the
+	// unprotected header
+	envelope := signDetachedJSON(t, `{"alg":"HS256","kid":"stream"}`, bytes.NewReader(nil), testHMACKey)
+
+	_, err := VerifyDetachedJSONReader(envelope, bytes.NewReader(nil), ProviderFromKey(testHMACKey))
+	if err == nil || err.Error() != "Duplicate JWS header parameter: kid" {
+		t.Fatalf("Verify succeeded or failed for another reason with a parameter in both headers: %v", err)
+	}
+}
+
+func TestVerifyDetachedJSONReader_UnprotectedAlg(t *testing.T) {
+	// correctly signed over a protected header that has no "alg"
+	envelope := signDetachedJSON(t, `{"typ":"JOSE"}`, bytes.NewReader(nil), testHMACKey)
+	var members map[string]interface{}
+	if err := json.Unmarshal(envelope, &members); err != nil {
+		t.Fatal("Unmarshal: ", err)
+	}
+
+	for _, unprotected := range []interface{}{nil, map[string]string{"alg": "HS256"}} {
+		members["header"] = unprotected
+		envelope, err := json.Marshal(members)
+		if err != nil {
+			t.Fatal("Marshal: ", err)
+		}
+		_, err = VerifyDetachedJSONReader(envelope, bytes.NewReader(nil), ProviderFromKey(testHMACKey))
+		if err == nil {
+			t.Fatalf("Verify succeeded with unprotected header %v", unprotected)
+		}
+	}
+}
+
+func TestVerifyDetachedJSONReader_HeaderOrder(t *testing.T) {
+	// key order and whitespace that json.Marshal of Header would not
+	// reproduce; verification must sign over the received bytes
+	const header = "{\"cty\":\"peer-1\",\r\n \"typ\":\"JOSE\",  \"alg\":\"HS256\"}"
+	const payload = `{"iss":"peer"}`
+	envelope := signDetachedJSON(t, header, bytes.NewReader([]byte(payload)), testHMACKey)
+
+	reserialized, err := json.Marshal(Header{Alg: ALG_HS256, Typ: "JOSE", Cty: "peer-1"})
+	if err != nil {
+		t.Fatal("Marshal: ", err)
+	}
+	if string(reserialized) == header {
+		t.Fatal("Test header must differ from its re-serialized form")
+	}
+
+	parsed, err := VerifyDetachedJSONReader(envelope, bytes.NewReader([]byte(payload)), ProviderFromKey(testHMACKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if parsed.Cty != "peer-1" || parsed.Typ != "JOSE" {
+		t.Fatalf("Unexpected header: %+v", parsed)
+	}
+
+	// the same holds for the compact serialization
+	jws := signHS256(header, payload, testHMACKey)
+	if _, err := VerifyAndDecode(jws, ProviderFromKey(testHMACKey)); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	compact := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	parts := strings.Split(compact, ".")
+
+	flattened := fmt.Sprintf(`{"payload":"%s","protected":"%s","signature":"%s"}`, parts[1], parts[0], parts[2])
+	for _, input := range []string{compact, " " + compact + "\n", flattened, "\n" + flattened} {
+		normalized, err := Normalize([]byte(input))
+		if err != nil {
+			t.Fatal("Normalize: ", err)
+		}
+		if normalized != compact {
+			t.Fatalf("Unexpected compact form: %s", normalized)
+		}
+		if _, err := VerifyAndDecode(normalized, ProviderFromKey(testHMACKey)); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+
+	invalid := map[string]string{
+		"unprotected header": fmt.Sprintf(`{"payload":"%s","protected":"%s","header":{"kid":"a"},"signature":"%s"}`, parts[1], parts[0], parts[2]),
+		"general":            fmt.Sprintf(`{"payload":"%s","signatures":[{"protected":"%s","signature":"%s"}]}`, parts[1], parts[0], parts[2]),
+		"malformed JSON":     `{"payload":"","protected":"e30","signature":""` + "x",
+		"truncated compact":  parts[0] + "." + parts[1],
+	}
+	for name, input := range invalid {
+		if _, err := Normalize([]byte(input)); err == nil {
+			t.Fatalf("%s: Normalize succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+)
+
+// build ES256 tokens over the same signing input carrying the low-S and
+// high-S forms of one signature
+func signES256LowHigh(t *testing.T, key *ecdsa.PrivateKey) (low, high string) {
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"joe"}`))
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal("ecdsa.Sign: ", err)
+	}
+
+	n := key.Curve.Params().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+	highS := new(big.Int).Sub(n, s)
+
+	encode := func(s *big.Int) string {
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	return encode(s), encode(highS)
+}
+
+func TestVerify_RequireLowS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	kp := ProviderFromKey(&key.PublicKey)
+	low, high := signES256LowHigh(t, key)
+
+	// both forms are valid ECDSA signatures by default
+	for _, jws := range []string{low, high} {
+		if _, err := VerifyAndDecode(jws, kp); err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+
+	opts := VerifyOptions{RequireLowS: true}
+	if _, err := VerifyWithOptions(low, kp, opts); err != nil {
+		t.Fatal("Verify low-S: ", err)
+	}
+	if _, err := VerifyWithOptions(high, kp, opts); err == nil {
+		t.Fatal("Verify succeeded for a high-S signature")
+	}
+}
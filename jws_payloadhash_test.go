@@ -0,0 +1,52 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestVerifyWithPayloadHash(t *testing.T) {
+	const payload = `{"iss":"joe","doc":"sha256"}`
+	jws := signHS256(`{"alg":"HS256"}`, payload, testHMACKey)
+	kp := ProviderFromKey(testHMACKey)
+
+	data, err := VerifyWithPayloadHash(jws, kp, sha256.Sum256([]byte(payload)))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(data) != payload {
+		t.Fatalf("Unexpected payload: %s", data)
+	}
+
+	if _, err := VerifyWithPayloadHash(jws, kp, sha256.Sum256([]byte(`{"iss":"joe"}`))); err == nil {
+		t.Fatal("Verify succeeded with a mismatched payload hash")
+	}
+
+	if _, err := VerifyWithPayloadHash(jws, ProviderFromKey([]byte("wrong")), sha256.Sum256([]byte(payload))); err == nil {
+		t.Fatal("Verify succeeded with the wrong key")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerify_PreProcess(t *testing.T) {
+	token := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	wrapped := "gw1:" + base64.StdEncoding.EncodeToString([]byte(token))
+
+	unwrap := func(s string) (string, error) {
+		if !strings.HasPrefix(s, "gw1:") {
+			return "", errors.New("missing envelope")
+		}
+		raw, err := base64.StdEncoding.DecodeString(s[len("gw1:"):])
+		return string(raw), err
+	}
+
+	kp := ProviderFromKey(testHMACKey)
+	if _, err := VerifyAndDecode(wrapped, kp); err == nil {
+		t.Fatal("Verify accepted an enveloped token without PreProcess")
+	}
+
+	result, err := VerifyWithOptions(wrapped, kp, VerifyOptions{PreProcess: unwrap})
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(result.Payload) != `{"iss":"joe"}` {
+		t.Fatalf("Unexpected payload: %s", result.Payload)
+	}
+
+	if _, err := VerifyWithOptions(token, kp, VerifyOptions{PreProcess: unwrap}); err == nil {
+		t.Fatal("Verify succeeded despite a PreProcess error")
+	}
+}
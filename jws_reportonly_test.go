@@ -0,0 +1,120 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerify_ReportOnly(t *testing.T) {
+	type report struct {
+		err      error
+		enforced bool
+	}
+	var reports []report
+	opts := VerifyOptions{
+		ReportOnly: &ReportOnlyOptions{Claims: &ClaimsOptions{Issuer: "joe"}},
+		OnFailure: func(err error, enforced bool) {
+			reports = append(reports, report{err, enforced})
+		},
+	}
+	kp := ProviderFromKey(testHMACKey)
+
+	// acceptable under both sets of options
+	if _, err := VerifyWithOptions(signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey), kp, opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+
+	// would be rejected by the strict options, but still succeeds
+	result, err := VerifyWithOptions(signHS256(`{"alg":"HS256"}`, `{"iss":"mallory"}`, testHMACKey), kp, opts)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(result.Payload) != `{"iss":"mallory"}` {
+		t.Fatalf("Unexpected payload: %s", result.Payload)
+	}
+	if len(reports) != 1 || reports[0].enforced || !errors.Is(reports[0].err, ErrInvalidIssuer) {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+
+	// enforced failures are reported too
+	reports = nil
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+	if _, err := VerifyWithOptions(jws[:len(jws)-2]+"AA", kp, opts); err == nil {
+		t.Fatal("Verify succeeded with a bad signature")
+	}
+	if len(reports) != 1 || !reports[0].enforced {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+
+	// the stricter checks reuse the verified token instead of
+	// verifying it again
+	reports = nil
+	counter := &countingProvider{key: testHMACKey}
+	strict := VerifyOptions{
+		ReportOnly: &ReportOnlyOptions{AllowedAlgorithms: []Algorithm{ALG_RS256}},
+		OnFailure:  opts.OnFailure,
+	}
+	if _, err := VerifyWithOptions(jws, counter, strict); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if counter.calls != 1 {
+		t.Fatalf("Key provider consulted %d times", counter.calls)
+	}
+	if len(reports) != 1 || reports[0].enforced || !errors.Is(reports[0].err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+}
+
+func TestVerify_ReportOnly_MaxRSABits(t *testing.T) {
+	privKey, err := keyFromJWK(testRSAKey)
+	if err != nil {
+		t.Fatal("keyFromJWK: ", err)
+	}
+	var reports []error
+	opts := VerifyOptions{
+		ReportOnly: &ReportOnlyOptions{MaxRSABits: 1024},
+		OnFailure: func(err error, enforced bool) {
+			if !enforced {
+				reports = append(reports, err)
+			}
+		},
+	}
+
+	// a provider handing out the private key is limited as when enforced
+	if _, err := VerifyWithOptions(testRS256Token, ProviderFromKey(privKey), VerifyOptions{MaxRSABits: 1024}); err == nil {
+		t.Fatal("Verify succeeded with an oversized RSA key")
+	}
+	if _, err := VerifyWithOptions(testRS256Token, ProviderFromKey(privKey), opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+}
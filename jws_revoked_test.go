@@ -0,0 +1,65 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+)
+
+type countingProvider struct {
+	key   crypto.PublicKey
+	calls int
+}
+
+func (cp *countingProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	cp.calls++
+	return cp.key, nil
+}
+
+func TestVerify_RevokedKid(t *testing.T) {
+	revoked := map[string]bool{"compromised": true}
+	opts := VerifyOptions{
+		IsRevokedKid: func(kid string) bool {
+			return revoked[kid]
+		},
+	}
+
+	kp := &countingProvider{key: testHMACKey}
+	jws := signHS256(`{"alg":"HS256","kid":"compromised"}`, `{"iss":"joe"}`, testHMACKey)
+	_, err := VerifyWithOptions(jws, kp, opts)
+	if !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("Expected ErrKeyRevoked, got %v", err)
+	}
+	if kp.calls != 0 {
+		t.Fatal("Key provider consulted for a revoked kid")
+	}
+
+	jws = signHS256(`{"alg":"HS256","kid":"current"}`, `{"iss":"joe"}`, testHMACKey)
+	if _, err := VerifyWithOptions(jws, kp, opts); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Content type checks for VerifyNested. Empty values default to "JWT".
+// Media types compare case-insensitively with any "application/"
+// prefix ignored (RFC 7515 4.1.9)
+type NestedOptions struct {
+	// Required "cty" of the outer token
+	OuterCty string
+
+	// Required "typ" of the inner token
+	InnerTyp string
+
+	// Options for verifying each layer
+	Outer VerifyOptions
+	Inner VerifyOptions
+}
+
+// Verify a nested JWS: an outer JWS whose payload is itself a compact
+// JWS (RFC 7519 5.2). Both layers are verified, each with its own key
+// provider, and their "cty" and "typ" checked against opts.
+func VerifyNested(jws string, outerKP, innerKP KeyProvider, opts NestedOptions) (outer, inner VerifyResult, err error) {
+	outerCty := opts.OuterCty
+	if outerCty == "" {
+		outerCty = "JWT"
+	}
+	innerTyp := opts.InnerTyp
+	if innerTyp == "" {
+		innerTyp = "JWT"
+	}
+
+	outer, err = VerifyWithOptions(jws, outerKP, opts.Outer)
+	if err != nil {
+		return
+	}
+	if !mediaTypeEqual(outer.Header.Cty, outerCty) {
+		err = fmt.Errorf("Nested JWS outer cty %q, expected %q", outer.Header.Cty, outerCty)
+		return
+	}
+
+	inner, err = VerifyWithOptions(string(outer.Payload), innerKP, opts.Inner)
+	if err != nil {
+		err = fmt.Errorf("Nested JWS: %w", err)
+		return
+	}
+	if !mediaTypeEqual(inner.Header.Typ, innerTyp) {
+		err = fmt.Errorf("Nested JWS inner typ %q, expected %q", inner.Header.Typ, innerTyp)
+	}
+	return
+}
+
+// compare media types as JOSE does, ignoring an "application/" prefix
+func mediaTypeEqual(a, b string) bool {
+	const prefix = "application/"
+	if len(a) > len(prefix) && strings.EqualFold(a[:len(prefix)], prefix) {
+		a = a[len(prefix):]
+	}
+	if len(b) > len(prefix) && strings.EqualFold(b[:len(prefix)], prefix) {
+		b = b[len(prefix):]
+	}
+	return strings.EqualFold(a, b)
+}
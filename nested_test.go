@@ -0,0 +1,72 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"testing"
+)
+
+func TestVerifyNested(t *testing.T) {
+	outerKey := []byte("outer signing key")
+	inner := signHS256(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"joe"}`, testHMACKey)
+
+	outer := signHS256(`{"alg":"HS256","cty":"JWT"}`, inner, outerKey)
+	_, result, err := VerifyNested(outer, ProviderFromKey(outerKey), ProviderFromKey(testHMACKey), NestedOptions{})
+	if err != nil {
+		t.Fatal("VerifyNested: ", err)
+	}
+	if string(result.Payload) != `{"sub":"joe"}` {
+		t.Fatalf("Unexpected payload: %s", result.Payload)
+	}
+
+	// media types compare case-insensitively, prefix or not
+	outer = signHS256(`{"alg":"HS256","cty":"application/jwt"}`, inner, outerKey)
+	if _, _, err := VerifyNested(outer, ProviderFromKey(outerKey), ProviderFromKey(testHMACKey), NestedOptions{}); err != nil {
+		t.Fatal("VerifyNested: ", err)
+	}
+}
+
+func TestVerifyNested_Mismatch(t *testing.T) {
+	outerKey := []byte("outer signing key")
+	outerKP, innerKP := ProviderFromKey(outerKey), ProviderFromKey(testHMACKey)
+	inner := signHS256(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"joe"}`, testHMACKey)
+
+	for _, header := range []string{`{"alg":"HS256"}`, `{"alg":"HS256","cty":"json"}`} {
+		outer := signHS256(header, inner, outerKey)
+		if _, _, err := VerifyNested(outer, outerKP, innerKP, NestedOptions{}); err == nil {
+			t.Fatalf("%s: VerifyNested accepted a mismatched cty", header)
+		}
+	}
+
+	// configured values replace the defaults
+	custom := signHS256(`{"alg":"HS256","typ":"at+jwt"}`, `{"sub":"joe"}`, testHMACKey)
+	outer := signHS256(`{"alg":"HS256","cty":"JWT"}`, custom, outerKey)
+	if _, _, err := VerifyNested(outer, outerKP, innerKP, NestedOptions{}); err == nil {
+		t.Fatal("VerifyNested accepted a mismatched inner typ")
+	}
+	if _, _, err := VerifyNested(outer, outerKP, innerKP, NestedOptions{InnerTyp: "at+jwt"}); err != nil {
+		t.Fatal("VerifyNested: ", err)
+	}
+}
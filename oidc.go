@@ -0,0 +1,178 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bound on discovery and JWK Set documents
+const maxOIDCDocumentSize = 1 << 20
+
+// Minimum time between JWK Set refreshes triggered by unknown kids, so
+// tokens with random kids cannot make every verification fetch
+const oidcRefreshInterval = time.Minute
+
+// Key provider for an OpenID Connect issuer, found through its discovery
+// document (OpenID Connect Discovery 1.0). Keys come from the jwks_uri
+// the issuer advertises and are fetched again when a token names an
+// unknown kid. Safe for concurrent use.
+type OIDCProvider struct {
+	issuer  string
+	jwksURI string
+	client  *http.Client
+
+	mu          sync.Mutex
+	keys        jwkSet
+	lastFetched time.Time     // last refresh attempt, successful or not
+	refreshing  chan struct{} // closed when the in-flight refresh ends
+}
+
+// Discover issuer's configuration and fetch its keys. The discovery
+// document must name the same issuer. httpClient may be nil to use
+// http.DefaultClient.
+func NewOIDCProvider(issuer string, httpClient *http.Client) (*OIDCProvider, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	p := &OIDCProvider{issuer: issuer, client: httpClient}
+
+	var config struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	data, err := p.fetch(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Failed to decode OIDC discovery document: %v", err)
+	}
+	if config.Issuer != issuer {
+		return nil, fmt.Errorf("%w: discovery document is for %q, not %q", ErrInvalidIssuer, config.Issuer, issuer)
+	}
+	if config.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document for %q has no jwks_uri", issuer)
+	}
+	p.jwksURI = config.JWKSURI
+
+	p.lastFetched = time.Now()
+	if p.keys, err = p.fetchKeys(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Issuer confirmed by discovery, for comparison with a verified "iss"
+func (p *OIDCProvider) Issuer() string {
+	return p.issuer
+}
+
+// JWK Set URL advertised by the issuer
+func (p *OIDCProvider) JWKSURI() string {
+	return p.jwksURI
+}
+
+func (p *OIDCProvider) GetJWSKey(h Header) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	if jwk, ok := p.keys.lookup(h); ok {
+		p.mu.Unlock()
+		return jwk, nil
+	}
+
+	// one caller refreshes, outside the lock, while the others wait for
+	// it. The attempt is recorded up front so a failing jwks_uri is
+	// also rate limited
+	done := p.refreshing
+	if done == nil {
+		if time.Since(p.lastFetched) < oidcRefreshInterval {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("Unknown kid %q for issuer %q", h.Kid, p.issuer)
+		}
+		p.lastFetched = time.Now()
+		done = make(chan struct{})
+		p.refreshing = done
+		p.mu.Unlock()
+
+		keys, err := p.fetchKeys()
+		p.mu.Lock()
+		if err == nil {
+			p.keys = keys
+		}
+		p.refreshing = nil
+		p.mu.Unlock()
+		close(done)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.mu.Unlock()
+		<-done
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if jwk, ok := p.keys.lookup(h); ok {
+		return jwk, nil
+	}
+	return nil, fmt.Errorf("Unknown kid %q for issuer %q", h.Kid, p.issuer)
+}
+
+// Fetch and decode the JWK Set. Does not touch p's key state
+func (p *OIDCProvider) fetchKeys() (jwkSet, error) {
+	data, err := p.fetch(p.jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKSet(data)
+	if err != nil {
+		return nil, &KeyFetchError{Err: err}
+	}
+	return keys, nil
+}
+
+func (p *OIDCProvider) fetch(url string) ([]byte, error) {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, &KeyFetchError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &KeyFetchError{Err: fmt.Errorf("GET %s: %s", url, resp.Status)}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOIDCDocumentSize))
+	if err != nil {
+		return nil, &KeyFetchError{Err: err}
+	}
+	return data, nil
+}
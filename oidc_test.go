@@ -0,0 +1,223 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mock OIDC issuer serving discovery and a mutable JWK Set
+type mockIssuer struct {
+	*httptest.Server
+	issuer string
+
+	mu          sync.Mutex
+	keys        []string
+	jwksFetches int
+	jwksStatus  int // served instead of the set when non-zero
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	m := &mockIssuer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   m.issuer,
+			"jwks_uri": m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.jwksFetches++
+		if m.jwksStatus != 0 {
+			w.WriteHeader(m.jwksStatus)
+			return
+		}
+		fmt.Fprintf(w, `{"keys":[%s]}`, strings.Join(m.keys, ","))
+	})
+	m.Server = httptest.NewServer(mux)
+	m.issuer = m.URL
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockIssuer) addKey(kid string, key *ecdsa.PublicKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jwk := ecJWK(key)
+	m.keys = append(m.keys, jwk[:len(jwk)-1]+`,"kid":"`+kid+`","use":"sig"}`)
+}
+
+func (m *mockIssuer) fetches() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jwksFetches
+}
+
+func TestOIDCProvider(t *testing.T) {
+	m := newMockIssuer(t)
+	k1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	m.addKey("k1", &k1.PublicKey)
+	// unusable keys are skipped
+	m.keys = append(m.keys, `{"kty":"oct","k":"c2VjcmV0","kid":"hmac"}`)
+
+	p, err := NewOIDCProvider(m.URL, m.Client())
+	if err != nil {
+		t.Fatal("NewOIDCProvider: ", err)
+	}
+	if p.Issuer() != m.URL || p.JWKSURI() != m.URL+"/jwks" {
+		t.Fatalf("Unexpected discovery: %s %s", p.Issuer(), p.JWKSURI())
+	}
+
+	jws := signES256(t, k1, `{"alg":"ES256","kid":"k1"}`, `{"iss":"`+m.URL+`"}`)
+	var claims struct {
+		Iss string `json:"iss"`
+	}
+	if err := VerifyAndDecodeClaims(jws, p, &claims); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if claims.Iss != p.Issuer() {
+		t.Fatalf("Unexpected iss: %s", claims.Iss)
+	}
+
+	// a rotated key is picked up by refreshing the set
+	k2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	m.addKey("k2", &k2.PublicKey)
+	rotated := signES256(t, k2, `{"alg":"ES256","kid":"k2"}`, `{"iss":"`+m.URL+`"}`)
+
+	// refreshes are rate limited
+	fetches := m.fetches()
+	if _, err := VerifyAndDecode(rotated, p); err == nil {
+		t.Fatal("Verify refreshed keys within the refresh interval")
+	}
+	if m.fetches() != fetches {
+		t.Fatal("Unknown kid triggered a fetch within the refresh interval")
+	}
+
+	p.lastFetched = time.Now().Add(-oidcRefreshInterval)
+	if _, err := VerifyAndDecode(rotated, p); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+func TestOIDCProvider_Errors(t *testing.T) {
+	m := newMockIssuer(t)
+	m.issuer = "https://impostor.example.com"
+	if _, err := NewOIDCProvider(m.URL, m.Client()); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("Expected ErrInvalidIssuer, got %v", err)
+	}
+
+	m.Close()
+	var fetchErr *KeyFetchError
+	if _, err := NewOIDCProvider(m.URL, m.Client()); !errors.As(err, &fetchErr) {
+		t.Fatalf("Expected *KeyFetchError, got %v", err)
+	}
+}
+
+func TestOIDCProvider_FailedRefresh(t *testing.T) {
+	m := newMockIssuer(t)
+	p, err := NewOIDCProvider(m.URL, m.Client())
+	if err != nil {
+		t.Fatal("NewOIDCProvider: ", err)
+	}
+	k1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	jws := signES256(t, k1, `{"alg":"ES256","kid":"k1"}`, `{"iss":"`+m.URL+`"}`)
+
+	m.mu.Lock()
+	m.jwksStatus = http.StatusInternalServerError
+	m.mu.Unlock()
+	p.lastFetched = time.Now().Add(-oidcRefreshInterval)
+	var fetchErr *KeyFetchError
+	if _, err := VerifyAndDecode(jws, p); !errors.As(err, &fetchErr) {
+		t.Fatalf("Expected *KeyFetchError, got %v", err)
+	}
+
+	// the failed attempt counts towards the rate limit
+	fetches := m.fetches()
+	if _, err := VerifyAndDecode(jws, p); err == nil {
+		t.Fatal("Verify succeeded with an unknown kid")
+	}
+	if m.fetches() != fetches {
+		t.Fatal("Failed refresh was retried within the refresh interval")
+	}
+}
+
+func TestOIDCProvider_ConcurrentRefresh(t *testing.T) {
+	m := newMockIssuer(t)
+	p, err := NewOIDCProvider(m.URL, m.Client())
+	if err != nil {
+		t.Fatal("NewOIDCProvider: ", err)
+	}
+	k1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+	m.addKey("k1", &k1.PublicKey)
+	jws := signES256(t, k1, `{"alg":"ES256","kid":"k1"}`, `{"iss":"`+m.URL+`"}`)
+
+	p.lastFetched = time.Now().Add(-oidcRefreshInterval)
+	fetches := m.fetches()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for ii := 0; ii < 8; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := VerifyAndDecode(jws, p)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal("Verify: ", err)
+		}
+	}
+	if m.fetches() != fetches+1 {
+		t.Fatalf("Expected a single refresh, got %d", m.fetches()-fetches)
+	}
+}
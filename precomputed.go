@@ -0,0 +1,101 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"fmt"
+	"sync"
+)
+
+// A compact JWS prepared for verification against several candidate
+// keys, as during key rotation. The header and signature are decoded
+// once, and for the RSA and ECDSA algorithms the signing input is
+// hashed once and the digest reused for every key. HMAC and EdDSA
+// cover the whole signing input with each key, so they are recomputed
+// on every attempt.
+type PrecomputedToken struct {
+	Header Header
+
+	parts     [3]string
+	signature []byte
+
+	digestOnce sync.Once
+	digest     []byte
+}
+
+// Parse and decode a compact JWS for repeated verification
+func PrecomputeToken(jws string) (*PrecomputedToken, error) {
+	parts, header, err := parseCompact(jws, safeDecode, &VerifyOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := safeDecode(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w signature: %v", ErrMalformedJWS, err)
+	}
+	return &PrecomputedToken{Header: header, parts: parts, signature: signature}, nil
+}
+
+// Verify the token's signature with key and return the decoded payload
+func (pt *PrecomputedToken) Verify(key crypto.PublicKey) (payload []byte, err error) {
+	defer wrapVerificationError(&err, pt.Header)
+
+	if jwk, ok := key.(*JWK); ok {
+		if err = jwk.permitsVerify(pt.Header.Alg); err != nil {
+			return
+		}
+		key = jwk.Key
+	}
+
+	sv, err := newSignatureVerifier(pt.Header.Alg, key, &VerifyOptions{})
+	if err != nil {
+		return
+	}
+	if sv.verifyDigest != nil {
+		pt.digestOnce.Do(func() {
+			hs := sv.hash.New()
+			writeSigningInput(hs, nil, pt.parts[0], pt.parts[1])
+			pt.digest = hs.Sum(nil)
+		})
+		err = sv.verifyDigest(pt.digest, pt.signature)
+	} else {
+		writeSigningInput(sv, nil, pt.parts[0], pt.parts[1])
+		err = sv.verify(pt.signature)
+	}
+	if err != nil {
+		return
+	}
+
+	if !pt.Header.payloadEncoded() {
+		return []byte(pt.parts[1]), nil
+	}
+	payload, err = safeDecode(pt.parts[1])
+	if err != nil {
+		err = fmt.Errorf("%w payload: %v", ErrMalformedJWS, err)
+	}
+	return
+}
@@ -0,0 +1,135 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// build a compact RS256 JWS
+func signRS256(t testing.TB, key *rsa.PrivateKey, header, payload string) string {
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	digest := sha256.Sum256([]byte(signingInput))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal("SignPKCS1v15: ", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestPrecomputedToken(t *testing.T) {
+	signer, err := keyFromJWK(testRSAKey)
+	if err != nil {
+		t.Fatal("keyFromJWK: ", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	pt, err := PrecomputeToken(testRS256Token)
+	if err != nil {
+		t.Fatal("PrecomputeToken: ", err)
+	}
+	if _, err := pt.Verify(&other.PublicKey); err == nil {
+		t.Fatal("Verify succeeded with the wrong key")
+	}
+	payload, err := pt.Verify(signer)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if !strings.HasPrefix(string(payload), `{"iss":"joe"`) {
+		t.Fatalf("Unexpected payload: %s", payload)
+	}
+}
+
+func TestPrecomputedToken_HMAC(t *testing.T) {
+	// each HMAC key yields a different MAC, so nothing may be shared
+	pt, err := PrecomputeToken(signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey))
+	if err != nil {
+		t.Fatal("PrecomputeToken: ", err)
+	}
+	for _, key := range [][]byte{[]byte("candidate one"), []byte("candidate two")} {
+		if _, err := pt.Verify(key); err == nil {
+			t.Fatal("Verify succeeded with the wrong HMAC key")
+		}
+	}
+	if _, err := pt.Verify(testHMACKey); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+}
+
+// verify a token with a large payload against several rotated keys,
+// the last of which signed it
+func benchmarkCandidateKeys(b *testing.B) (string, []crypto.PublicKey) {
+	var keys []crypto.PublicKey
+	var signer *rsa.PrivateKey
+	for ii := 0; ii < 4; ii++ {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			b.Fatal("GenerateKey: ", err)
+		}
+		keys = append(keys, &key.PublicKey)
+		signer = key
+	}
+	payload := `{"data":"` + strings.Repeat("x", 64<<10) + `"}`
+	return signRS256(b, signer, `{"alg":"RS256"}`, payload), keys
+}
+
+func BenchmarkMultiKey_VerifyAndDecode(b *testing.B) {
+	jws, keys := benchmarkCandidateKeys(b)
+	b.ResetTimer()
+	for ii := 0; ii < b.N; ii++ {
+		for _, key := range keys {
+			if _, err := VerifyAndDecode(jws, ProviderFromKey(key)); err == nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkMultiKey_PrecomputedToken(b *testing.B) {
+	jws, keys := benchmarkCandidateKeys(b)
+	b.ResetTimer()
+	for ii := 0; ii < b.N; ii++ {
+		pt, err := PrecomputeToken(jws)
+		if err != nil {
+			b.Fatal("PrecomputeToken: ", err)
+		}
+		for _, key := range keys {
+			if _, err := pt.Verify(key); err == nil {
+				break
+			}
+		}
+	}
+}
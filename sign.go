@@ -0,0 +1,181 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Digest used by each hash-based algorithm
+var algHashes = map[Algorithm]crypto.Hash{
+	ALG_HS256: crypto.SHA256,
+	ALG_HS384: crypto.SHA384,
+	ALG_HS512: crypto.SHA512,
+	ALG_RS256: crypto.SHA256,
+	ALG_RS384: crypto.SHA384,
+	ALG_RS512: crypto.SHA512,
+	ALG_ES256: crypto.SHA256,
+	ALG_ES384: crypto.SHA384,
+	ALG_ES512: crypto.SHA512,
+	ALG_PS256: crypto.SHA256,
+	ALG_PS384: crypto.SHA384,
+	ALG_PS512: crypto.SHA512,
+}
+
+// Optional behavior for SignWithOptions. The zero value signs exactly
+// like SignAndEncode
+type SignOptions struct {
+	// Non-standard domain separation: bytes prepended to the signing
+	// input before signing. Tokens verify only with the same
+	// VerifyOptions.SigningContext. Empty for standard JWS
+	SigningContext []byte
+}
+
+// Produce a compact JWS of payload signed with key. header supplies any
+// additional parameters; its "alg" is set to alg. Keys are []byte for
+// HMAC, *rsa.PrivateKey for RS and PS, *ecdsa.PrivateKey for ES and
+// ed25519.PrivateKey for EdDSA. As with verification, "none" is only
+// produced when key is NoneKey.
+func SignAndEncode(payload []byte, alg Algorithm, key crypto.PrivateKey, header Header) (string, error) {
+	return SignWithOptions(payload, alg, key, header, SignOptions{})
+}
+
+// Produce a compact JWS as SignAndEncode does, applying opts
+func SignWithOptions(payload []byte, alg Algorithm, key crypto.PrivateKey, header Header, opts SignOptions) (string, error) {
+	header.Alg = alg
+	if err := header.checkExtensions(); err != nil {
+		return "", err
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode header: %v", err)
+	}
+
+	encodedPayload := string(payload)
+	if header.payloadEncoded() {
+		encodedPayload = base64.RawURLEncoding.EncodeToString(payload)
+	} else if strings.Contains(encodedPayload, ".") {
+		return "", ErrUnencodedPayloadDot
+	}
+
+	signingInput := SigningInput(base64.RawURLEncoding.EncodeToString(headerJSON), encodedPayload)
+	signature, err := ComputeSignature(alg, key, append(append([]byte{}, opts.SigningContext...), signingInput...))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// The JWS signing input (RFC 7515 5.1) for an encoded protected header
+// and payload
+func SigningInput(headerB64, payloadB64 string) string {
+	return headerB64 + "." + payloadB64
+}
+
+// Compute the raw signature of signingInput for alg, in the form the
+// verifier consumes: the MAC for HMAC, fixed-width R||S for ECDSA and
+// the plain signature otherwise. Keys are as for SignAndEncode. The
+// result is base64url encoded to form a compact or JSON serialization.
+func ComputeSignature(alg Algorithm, key crypto.PrivateKey, signingInput []byte) ([]byte, error) {
+	if alg == ALG_NONE {
+		if key != NoneKey {
+			return nil, errors.New("Refusing to produce plaintext JWS")
+		}
+		return nil, nil
+	}
+	if alg == ALG_EDDSA {
+		privKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Expected Ed25519 private key. Got %T", key)
+		}
+		if len(privKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("Malformed Ed25519 private key of %d bytes", len(privKey))
+		}
+		return ed25519.Sign(privKey, signingInput), nil
+	}
+
+	htype, ok := algHashes[alg]
+	if !ok {
+		return nil, fmt.Errorf("Unknown signature algorithm: %s", alg)
+	}
+
+	switch alg {
+	case ALG_HS256, ALG_HS384, ALG_HS512:
+		symmetricKey, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("Expected symmetric ([]byte) key. Got %T", key)
+		}
+		hm := hmac.New(htype.New, symmetricKey)
+		hm.Write(signingInput)
+		return hm.Sum(nil), nil
+	}
+
+	hs := htype.New()
+	hs.Write(signingInput)
+	digest := hs.Sum(nil)
+
+	switch alg {
+	case ALG_RS256, ALG_RS384, ALG_RS512, ALG_PS256, ALG_PS384, ALG_PS512:
+		privKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Expected RSA private key. Got %T", key)
+		}
+		if alg == ALG_RS256 || alg == ALG_RS384 || alg == ALG_RS512 {
+			return rsa.SignPKCS1v15(rand.Reader, privKey, htype, digest)
+		}
+		return rsa.SignPSS(rand.Reader, privKey, htype, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+
+	default:
+		privKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Expected ECDSA private key. Got %T", key)
+		}
+		curve := ecdsaCurves[alg]
+		if privKey.Curve != curve {
+			return nil, fmt.Errorf("%w: %s requires %s", ErrCurveMismatch, alg, curve.Params().Name)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, privKey, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		// fixed-width R || S (RFC 7518 3.4)
+		size := (curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	}
+}
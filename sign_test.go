@@ -0,0 +1,185 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+type signingKey struct {
+	alg    Algorithm
+	key    crypto.PrivateKey
+	verify crypto.PublicKey
+}
+
+// a signing and verification key pair for every algorithm
+func signingKeys(t *testing.T) []signingKey {
+	rsaKey, err := keyFromJWK(testRSAKey)
+	if err != nil {
+		t.Fatal("keyFromJWK: ", err)
+	}
+	ecKeys := make(map[elliptic.Curve]*ecdsa.PrivateKey)
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		if ecKeys[curve], err = ecdsa.GenerateKey(curve, rand.Reader); err != nil {
+			t.Fatal("GenerateKey: ", err)
+		}
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	return []signingKey{
+		{ALG_HS256, testHMACKey, testHMACKey},
+		{ALG_HS384, testHMACKey, testHMACKey},
+		{ALG_HS512, testHMACKey, testHMACKey},
+		{ALG_RS256, rsaKey, rsaKey},
+		{ALG_RS384, rsaKey, rsaKey},
+		{ALG_RS512, rsaKey, rsaKey},
+		{ALG_PS256, rsaKey, rsaKey},
+		{ALG_PS384, rsaKey, rsaKey},
+		{ALG_PS512, rsaKey, rsaKey},
+		{ALG_ES256, ecKeys[elliptic.P256()], &ecKeys[elliptic.P256()].PublicKey},
+		{ALG_ES384, ecKeys[elliptic.P384()], &ecKeys[elliptic.P384()].PublicKey},
+		{ALG_ES512, ecKeys[elliptic.P521()], &ecKeys[elliptic.P521()].PublicKey},
+		{ALG_EDDSA, edPriv, edPub},
+		{ALG_NONE, NoneKey, NoneKey},
+	}
+}
+
+func TestSignAndEncode_RoundTrip(t *testing.T) {
+	tests := signingKeys(t)
+	payload := []byte(`{"iss":"joe","exp":1300819380}`)
+	for _, tt := range tests {
+		jws, err := SignAndEncode(payload, tt.alg, tt.key, Header{Typ: "JWT", Kid: "k1"})
+		if err != nil {
+			t.Fatalf("%s: SignAndEncode: %v", tt.alg, err)
+		}
+
+		header, decoded, err := VerifyAndDecodeWithHeader(jws, ProviderFromKey(tt.verify))
+		if err != nil {
+			t.Fatalf("%s: Verify: %v", tt.alg, err)
+		}
+		if string(decoded) != string(payload) {
+			t.Fatalf("%s: Unexpected payload: %s", tt.alg, decoded)
+		}
+		if header.Alg != tt.alg || header.Typ != "JWT" || header.Kid != "k1" {
+			t.Fatalf("%s: Unexpected header: %+v", tt.alg, header)
+		}
+	}
+}
+
+func TestSignAndEncode_Unencoded(t *testing.T) {
+	b64 := false
+	header := Header{B64: &b64, Crit: []string{"b64"}}
+
+	jws, err := SignAndEncode([]byte("$.02"), ALG_HS256, testHMACKey, header)
+	if err == nil {
+		t.Fatalf("SignAndEncode produced a compact JWS with '.' in an unencoded payload: %s", jws)
+	}
+
+	jws, err = SignAndEncode([]byte("$02"), ALG_HS256, testHMACKey, header)
+	if err != nil {
+		t.Fatal("SignAndEncode: ", err)
+	}
+	payload, err := VerifyAndDecode(jws, ProviderFromKey(testHMACKey))
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if string(payload) != "$02" {
+		t.Fatalf("Unexpected payload: %s", payload)
+	}
+}
+
+func TestSignAndEncode_HeaderRules(t *testing.T) {
+	b64 := false
+
+	// headers the verifier would reject are not signed
+	for _, header := range []Header{
+		{B64: &b64},
+		{Crit: []string{"exp"}},
+		{Crit: []string{"b64", "b64"}},
+		{Crit: []string{}},
+	} {
+		if jws, err := SignAndEncode([]byte("$02"), ALG_HS256, testHMACKey, header); err == nil {
+			t.Fatalf("SignAndEncode signed %+v: %s", header, jws)
+		}
+	}
+}
+
+func TestSignAndEncode_Rejects(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey: ", err)
+	}
+
+	tests := []struct {
+		alg Algorithm
+		key crypto.PrivateKey
+	}{
+		// plaintext needs the explicit NoneKey
+		{ALG_NONE, testHMACKey},
+		{ALG_NONE, nil},
+		{ALG_HS256, ecKey},
+		{ALG_RS256, testHMACKey},
+		{ALG_ES512, ecKey},
+		{ALG_EDDSA, ecKey},
+		{Algorithm("HS1"), testHMACKey},
+	}
+	for _, tt := range tests {
+		if jws, err := SignAndEncode([]byte(`{}`), tt.alg, tt.key, Header{}); err == nil {
+			t.Fatalf("%s with %T: SignAndEncode succeeded: %s", tt.alg, tt.key, jws)
+		}
+	}
+}
+
+func TestComputeSignature(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"joe"}`))
+	for _, tt := range signingKeys(t) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + string(tt.alg) + `"}`))
+		signingInput := SigningInput(header, payload)
+		if signingInput != header+"."+payload {
+			t.Fatalf("Unexpected signing input: %s", signingInput)
+		}
+
+		signature, err := ComputeSignature(tt.alg, tt.key, []byte(signingInput))
+		if err != nil {
+			t.Fatalf("%s: ComputeSignature: %v", tt.alg, err)
+		}
+		if err := VerifyParts(signingInput, signature, ProviderFromKey(tt.verify)); err != nil {
+			t.Fatalf("%s: VerifyParts: %v", tt.alg, err)
+		}
+		jws := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+		if _, err := VerifyAndDecode(jws, ProviderFromKey(tt.verify)); err != nil {
+			t.Fatalf("%s: Verify: %v", tt.alg, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Verifies compact JWS and streams their payload, decoding it in chunks
+// as it is read rather than into a single allocation. Meant for large
+// payloads such as signed bulk exports. It has no claim settings, since
+// checking claims would require decoding the whole payload up front.
+// Safe for concurrent use.
+type StreamVerifier struct {
+	kp   KeyProvider
+	opts VerifyOptions
+}
+
+// Create a StreamVerifier selecting keys with kp. opts must not set
+// Claims or ReportOnly, which need the decoded payload, nor
+// AllowStdBase64, which the streaming decoder does not support
+func NewStreamVerifier(kp KeyProvider, opts VerifyOptions) (StreamVerifier, error) {
+	if kp == nil {
+		return StreamVerifier{}, errors.New("StreamVerifier has no key provider")
+	}
+	if opts.Claims != nil || opts.ReportOnly != nil {
+		return StreamVerifier{}, errors.New("StreamVerifier cannot check claims")
+	}
+	if opts.AllowStdBase64 {
+		return StreamVerifier{}, errors.New("StreamVerifier does not support AllowStdBase64")
+	}
+	return StreamVerifier{kp: kp, opts: opts}, nil
+}
+
+// Verify a compact JWS and return a reader over its payload. The result
+// carries no Payload, and the reader is only returned once the
+// signature is verified
+func (sv StreamVerifier) Verify(jws string) (result VerifyResult, payload io.Reader, err error) {
+	opts := sv.opts
+	if opts.OnFailure != nil {
+		defer func() {
+			if err != nil {
+				opts.OnFailure(err, true)
+			}
+		}()
+	}
+
+	result, segment, _, err := verifyToken(jws, sv.kp, &opts, false)
+	if err != nil {
+		return
+	}
+	payload = strings.NewReader(segment)
+	if result.Header.payloadEncoded() {
+		payload = base64.NewDecoder(base64.RawURLEncoding.Strict(), payload)
+	}
+	return
+}
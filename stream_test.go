@@ -0,0 +1,123 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamVerifier(t *testing.T) {
+	var export bytes.Buffer
+	export.WriteString(`{"rows":[`)
+	for ii := 0; ii < 100000; ii++ {
+		if ii > 0 {
+			export.WriteByte(',')
+		}
+		fmt.Fprintf(&export, `{"id":%d,"sku":"item-%x"}`, ii, ii*7919)
+	}
+	export.WriteString(`]}`)
+	jws := signHS256(`{"alg":"HS256"}`, export.String(), testHMACKey)
+	kp := ProviderFromKey(testHMACKey)
+
+	expected, err := VerifyAndDecode(jws, kp)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	sv, err := NewStreamVerifier(kp, VerifyOptions{AllowedAlgorithms: []Algorithm{ALG_HS256}})
+	if err != nil {
+		t.Fatal("NewStreamVerifier: ", err)
+	}
+	result, stream, err := sv.Verify(jws)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	if result.Header.Alg != ALG_HS256 || result.Payload != nil {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	streamed, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal("ReadAll: ", err)
+	}
+	if !bytes.Equal(streamed, expected) {
+		t.Fatalf("Streamed payload differs: %d bytes, expected %d", len(streamed), len(expected))
+	}
+
+	// nothing is streamed without a valid signature
+	dot := strings.LastIndexByte(jws, '.')
+	tampered := jws[:dot-4] + "AAAA" + jws[dot:]
+	if _, stream, err := sv.Verify(tampered); err == nil || stream != nil {
+		t.Fatalf("Verify succeeded for a tampered payload: %v", err)
+	}
+	sv, err = NewStreamVerifier(kp, VerifyOptions{AllowedAlgorithms: []Algorithm{ALG_RS256}})
+	if err != nil {
+		t.Fatal("NewStreamVerifier: ", err)
+	}
+	if _, _, err := sv.Verify(jws); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("Expected ErrAlgorithmNotAllowed, got %v", err)
+	}
+}
+
+func TestStreamVerifier_SharedOptions(t *testing.T) {
+	jws := signHS256(`{"alg":"HS256"}`, `{"iss":"joe"}`, testHMACKey)
+
+	// PreProcess and MultiKeyProvider apply as in VerifyWithOptions
+	kp := rotatingKeys{[]byte("current signing key"), testHMACKey}
+	sv, err := NewStreamVerifier(kp, VerifyOptions{
+		PreProcess: func(jws string) (string, error) {
+			return strings.TrimPrefix(jws, "Bearer "), nil
+		},
+	})
+	if err != nil {
+		t.Fatal("NewStreamVerifier: ", err)
+	}
+	_, stream, err := sv.Verify("Bearer " + jws)
+	if err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	streamed, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal("ReadAll: ", err)
+	}
+	if string(streamed) != `{"iss":"joe"}` {
+		t.Fatalf("Unexpected payload: %s", streamed)
+	}
+
+	// options that need the decoded payload are refused
+	for name, opts := range map[string]VerifyOptions{
+		"Claims":         {Claims: &ClaimsOptions{ValidateExpiry: true}},
+		"ReportOnly":     {ReportOnly: &ReportOnlyOptions{}},
+		"AllowStdBase64": {AllowStdBase64: true},
+	} {
+		if _, err := NewStreamVerifier(kp, opts); err == nil {
+			t.Fatalf("NewStreamVerifier accepted %s", name)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Regenerates the interopTokens fixtures in jws_interop_test.go with the
+// Node.js crypto module and no JOSE library. Run with Node.js v20.19.5:
+//
+//	node testdata/interop/node-crypto.js
+//
+// HMAC, RS* and EdDSA output is deterministic and must match the
+// fixtures exactly; PS* and ES* signatures are randomized, so those
+// lines differ on every run but verify all the same.
+'use strict';
+const crypto = require('crypto');
+
+const keys = {
+	interopOctKey: {"kty":"oct","k":"r0CHQGpowv-9zGTb2GNTa-3sQqWCpVhc0MEZ4mggZkd75He_MDeAl5oT0wUVc54rns03gLZffUopOsvwFVquAQ"},
+	interopRSAKey: {"kty":"RSA","n":"lBeU7QKCwlHIIXi3qM3zleiTtufYOb7_YYZLawTuxf7ZjP5OUFwpzMcwKwzhDLwh2Cz7gbWLFL9V84PiHKJOqObIF5EI5M3s9LHxr4BCmK0amD55NV0ql_tHA7OsPI8bDNKH7e7UWRcx5R1wIsUAZNSfVga1-MFO2P0cr7Y-1FwrUaNrhwEWj3frtP_FEec7O8__hgWFe5jXYYHG8vmh8DKJFiBVIiS1qA5Us0X_d2efDu_fV4yrmse8S7GFfzuKZ5KC3yvuj1LFE1bO40OvCoumWlcOHnM7gKgvFNQU19VxHcRmSiz5FnIicQCjaclfmkwApHXAGymlFzpGg07bSQ","e":"AQAB","d":"Gg9GRSi-csxqX7168CkBtrz9FWAZ9BtwnCxCJDxzegL9LGrpxAlbz62zg7fZ-GA4wm4z-eDE7ys2SEpKZUiId_9YcZEzk_PKwUp44a27ylsXxXek46eX4bO1jOi2o9d45po7kjqHQoAq5wr1myC4j4W83ioDJpl5swRjYmY7CypCbFhCnb4t2YL90z9RJDqwkC8Q77K9qWkrvBBqyz5orHVRY-OSxrIqkcTQk5IVnO59PXkZO2SXD8K_4ZDYiqII72XE4_QizUG3rL8KdLFGWMmiXwhGyYK2wG_C_OSdkjN5h-Hc_rKgA9tqpuPg4AnxBXwrorRmQTMFV-q3TkQbyQ","p":"wx_mGmA1NyfbLhZdcrUiCssjsraSyohzS6wuksnKtIvzoPjuNulsUnN1rbCzgXtBY-I4SuAx_ewHoJfqRc2CPTGJaPtpDRsSNRSsqhfckv-lbhG3KHxqi-P_wKrnL7QVV2ngdkoFQST3n0iJMd9Uxfs6j0lxy9jQZwNsXClAzs0","q":"wktRnM1_cUOy_3nqSmGRndAqo1gKW5VOyWFvVPosnAQjmzno7Vis_nXtkE3MU0Z_7hTV4w8VGXJgLQKCk5TafH6ecZydmrYGXEj31Sz947aitjia-RgmcqcL3pmG6BLpfqm52kGU3K58cYD1cgJDS_OjhqsjiwEoXHqloclWBm0","dp":"uTE4WoY_W6b3TsmpTap-sdHVgtPnhFR3YTX9ShI90pgV1g0NQo7O4X52DTDtXhIWoO9fUClzKTXwk0o77p47Y0r1yO786JRt9nU134YgMmByqYIk3UcgdL8jyMAsBcmci8eQY5SjYg3s-3-DvxLhSbrE_KPPXpLcUTkdimcY_G0","dq":"HCuLc66f0a49H6lvfMVUtYrfA7vaFJk7SRtlZu621RAMkY795Ju8yVAT155QB6rrFXvT3w3qwzvAEgaRJBCq9O3uzw4kE7Bcj7jMpg3V6dQrFzxxy7fiqzDpGacs1i0XyZOWLLzhdnWd-V73N0Z4t2Rlyzq49oh-b5ogtapZXKk","qi":"AhcCsHdRP6g-SsPC3r5fUJPYbI05-lCWOh92eXjp4SDMHcg-ONiQ55EE3-_YmmRYzwNGAcdH9ZgrZIMKaWIrq3u6klxybKXtUCVqCcX9NLRBMG2smL_3VptwwXxVCOM5nfHu4cWZ1ZgBLok89PcKlpvdhiyTTmSWKLspOx37Vo0"},
+	interopP256Key: {"kty":"EC","x":"MncCfugl-eDROcHUqveZLTiIvgE2AGCWwntRykqm3zo","y":"usldy9Artjv7q74zsnsRT64XCniMrIfSS39xCBMSqrU","crv":"P-256","d":"TaQu4qhM-Kacji3nk6Y9k6IDnvLyjFJ5zEzHkU9P8QU"},
+	interopP384Key: {"kty":"EC","x":"ISulYSNHOJOwCjHM8vAmjTywWJE9XdIMoxrv1EJWQfpJlb_WiwX0IwPlrdxSRoow","y":"CIY5-AUdy1EzzcXhRLNnl1Qze-JGJwFgyaf-2vIqeFq91UtDookU5kmkiqR_d_at","crv":"P-384","d":"AWPUXLMU2Hm8mfZykhd2K0TtePDjMsd7-ta__7oPlQy7PAkpBTye9F-3wL_AC-Kd"},
+	interopP521Key: {"kty":"EC","x":"AZ2b-ovRkmdiuEc7-61ot46ipgR-fd3IFnA4g5Cb0bU38Gfpt7RurjYlW1qv42KZ46eG94_eRbJ71zBuP5a_P91w","y":"AJTl4o2BWVNSJF1JeLkLqCnWG89dwyWqJ7hcFOGqr7zuopDqlGT0zufNoU1HA_iGpp6DlwaIuc19FTVf7lxIg4Uf","crv":"P-521","d":"AGbxywk20pI9x633mjzdtQhePnMMPKpiLrxOrvlNYVT6b5ttb2OIQI4SFPaxgCKUK3vZh58ubcj0wxAAnafptKpE"},
+	interopEd25519Key: {"kty":"OKP","crv":"Ed25519","x":"-2cTYI16NzO_CSGT4yFXS8R2WDy_aS8Q0zhvAr0thEM","d":"Gc6yIcxLfOYwX1yHfNyMsSn-ACGB46X-3PN-0I2ttsQ"},
+};
+
+const b64url = (buf) => Buffer.from(buf).toString('base64url');
+const secret = Buffer.from(keys.interopOctKey.k, 'base64url');
+const privateKey = (jwk) => crypto.createPrivateKey({ key: jwk, format: 'jwk' });
+
+const payload = b64url(JSON.stringify({ iss: 'node', sub: 'interop', n: 1 }));
+
+function sign(alg, key, keyName) {
+	const input = b64url(JSON.stringify({ alg, typ: 'JWT' })) + '.' + payload;
+	const bits = alg.slice(2);
+	let signature;
+	switch (alg.slice(0, 2)) {
+	case 'HS':
+		signature = crypto.createHmac('sha' + bits, secret).update(input).digest();
+		break;
+	case 'RS':
+		signature = crypto.sign('sha' + bits, Buffer.from(input), privateKey(key));
+		break;
+	case 'PS':
+		signature = crypto.sign('sha' + bits, Buffer.from(input), {
+			key: privateKey(key),
+			padding: crypto.constants.RSA_PKCS1_PSS_PADDING,
+			saltLength: bits / 8,
+		});
+		break;
+	case 'ES':
+		signature = crypto.sign('sha' + bits, Buffer.from(input), {
+			key: privateKey(key),
+			dsaEncoding: 'ieee-p1363',
+		});
+		break;
+	default:
+		signature = crypto.sign(null, Buffer.from(input), privateKey(key));
+	}
+	console.log('\t{ALG_%s, %s, `%s.%s`},', alg.toUpperCase(), keyName, input, b64url(signature));
+}
+
+for (const alg of ['HS256', 'HS384', 'HS512']) sign(alg, null, 'interopOctKey');
+for (const alg of ['RS256', 'RS384', 'RS512', 'PS256', 'PS384', 'PS512']) sign(alg, keys.interopRSAKey, 'interopRSAKey');
+sign('ES256', keys.interopP256Key, 'interopP256Key');
+sign('ES384', keys.interopP384Key, 'interopP384Key');
+sign('ES512', keys.interopP521Key, 'interopP521Key');
+sign('EdDSA', keys.interopEd25519Key, 'interopEd25519Key');
@@ -0,0 +1,90 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"time"
+)
+
+// Reusable verification settings built up with the With methods, each
+// of which returns a modified copy and leaves the receiver untouched.
+// A Verifier checks "exp" and "nbf" by default and is safe for
+// concurrent use.
+//
+//	v := NewVerifier().
+//		WithAllowedAlgs(ALG_RS256, ALG_ES256).
+//		WithProvider(kp).
+//		WithLeeway(30 * time.Second).
+//		WithExpectedIssuer("https://issuer.example.com")
+//	result, err := v.Verify(jws)
+type Verifier struct {
+	kp     KeyProvider
+	opts   VerifyOptions
+	claims ClaimsOptions
+}
+
+// Start a Verifier with no key provider, algorithm restriction or
+// leeway
+func NewVerifier() Verifier {
+	return Verifier{
+		claims: ClaimsOptions{ValidateExpiry: true, ValidateNotBefore: true},
+	}
+}
+
+// Only accept tokens using one of algs
+func (v Verifier) WithAllowedAlgs(algs ...Algorithm) Verifier {
+	v.opts.AllowedAlgorithms = append([]Algorithm{}, algs...)
+	return v
+}
+
+// Select verification keys with kp
+func (v Verifier) WithProvider(kp KeyProvider) Verifier {
+	v.kp = kp
+	return v
+}
+
+// Tolerate clock skew of up to leeway in the "exp" and "nbf" checks
+func (v Verifier) WithLeeway(leeway time.Duration) Verifier {
+	v.claims.Leeway = leeway
+	return v
+}
+
+// Require the "iss" claim to equal issuer
+func (v Verifier) WithExpectedIssuer(issuer string) Verifier {
+	v.claims.Issuer = issuer
+	return v
+}
+
+// Verify a compact JWS with the configured settings
+func (v Verifier) Verify(jws string) (VerifyResult, error) {
+	if v.kp == nil {
+		return VerifyResult{}, errors.New("Verifier has no key provider")
+	}
+	opts := v.opts
+	claims := v.claims
+	opts.Claims = &claims
+	return VerifyWithOptions(jws, v.kp, opts)
+}
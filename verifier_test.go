@@ -0,0 +1,113 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	now := time.Now().Unix()
+	sign := func(alg Algorithm, payload string) string {
+		jws, err := SignAndEncode([]byte(payload), alg, testHMACKey, Header{})
+		if err != nil {
+			t.Fatal("SignAndEncode: ", err)
+		}
+		return jws
+	}
+	base := NewVerifier().
+		WithAllowedAlgs(ALG_HS256).
+		WithProvider(ProviderFromKey(testHMACKey)).
+		WithExpectedIssuer("joe")
+	v := base.WithLeeway(time.Minute)
+
+	valid := sign(ALG_HS256, fmtClaims("joe", now+3600))
+	if _, err := v.Verify(valid); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+
+	// expired, but within the leeway
+	skewed := sign(ALG_HS256, fmtClaims("joe", now-30))
+	if _, err := v.Verify(skewed); err != nil {
+		t.Fatal("Verify: ", err)
+	}
+	// the original verifier is unaffected by WithLeeway
+	if _, err := base.Verify(skewed); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired, got %v", err)
+	}
+
+	expired := sign(ALG_HS256, fmtClaims("joe", now-3600))
+	if _, err := v.Verify(expired); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired, got %v", err)
+	}
+
+	wrongIssuer := sign(ALG_HS256, fmtClaims("mallory", now+3600))
+	if _, err := v.Verify(wrongIssuer); !errors.Is(err, ErrInvalidIssuer) {
+		t.Fatalf("Expected ErrInvalidIssuer, got %v", err)
+	}
+
+	wrongAlg := sign(ALG_HS512, fmtClaims("joe", now+3600))
+	if _, err := v.Verify(wrongAlg); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Fatalf("Expected ErrAlgorithmNotAllowed, got %v", err)
+	}
+
+	if _, err := NewVerifier().Verify(valid); err == nil {
+		t.Fatal("Verify succeeded without a key provider")
+	}
+}
+
+func TestVerifier_Concurrent(t *testing.T) {
+	v := NewVerifier().WithProvider(ProviderFromKey(testHMACKey)).WithExpectedIssuer("joe")
+	valid := signHS256(`{"alg":"HS256"}`, fmtClaims("joe", time.Now().Unix()+3600), testHMACKey)
+	invalid := signHS256(`{"alg":"HS256"}`, fmtClaims("mallory", time.Now().Unix()+3600), testHMACKey)
+
+	var wg sync.WaitGroup
+	for ii := 0; ii < 16; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jj := 0; jj < 50; jj++ {
+				_, err := v.Verify(valid)
+				if err != nil {
+					t.Error("Verify: ", err)
+					return
+				}
+				if _, err := v.Verify(invalid); err == nil {
+					t.Error("Verify accepted the wrong issuer")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func fmtClaims(iss string, exp int64) string {
+	return fmt.Sprintf(`{"iss":%q,"exp":%d}`, iss, exp)
+}
@@ -0,0 +1,352 @@
+// Copyright 2014 Matthew Endsley
+// All rights reserved
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted providing that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+// WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY
+// DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT,
+// STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING
+// IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package gojws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// Returned for every signature that does not verify, whatever the
+// reason, so failures cannot be told apart by their error. Problems
+// with the configured key are reported separately, before the
+// signature is examined
+var ErrVerificationFailed = errors.New("Signature verification failed")
+
+// Returned when an ECDSA key is not on the curve the algorithm requires
+var ErrCurveMismatch = errors.New("Key curve does not match the JWS algorithm")
+
+// Curve mandated by each ECDSA algorithm (RFC 7518 3.4)
+var ecdsaCurves = map[Algorithm]elliptic.Curve{
+	ALG_ES256: elliptic.P256(),
+	ALG_ES384: elliptic.P384(),
+	ALG_ES512: elliptic.P521(),
+}
+
+// Signature check for a single algorithm and key. The signing input is
+// written to the verifier, then the signature is checked by verify.
+type signatureVerifier struct {
+	input  io.Writer // nil for "none"
+	verify func(signature []byte) error
+
+	// Algorithms that sign a digest of the input also accept a digest
+	// made with hash, letting callers hash the signing input once
+	hash         crypto.Hash
+	verifyDigest func(digest, signature []byte) error
+}
+
+// Build a verifier for an algorithm that signs an htype digest
+func digestVerifier(htype crypto.Hash, verifyDigest func(digest, signature []byte) error) *signatureVerifier {
+	hs := htype.New()
+	return &signatureVerifier{
+		input: hs,
+		verify: func(signature []byte) error {
+			return verifyDigest(hs.Sum(nil), signature)
+		},
+		hash:         htype,
+		verifyDigest: verifyDigest,
+	}
+}
+
+func (sv *signatureVerifier) Write(p []byte) (int, error) {
+	if sv.input == nil {
+		return len(p), nil
+	}
+	return sv.input.Write(p)
+}
+
+// Accept an RSA public or private key with a sane exponent, within the
+// configured size limit
+func rsaVerificationKey(key crypto.PublicKey, opts *VerifyOptions) (*rsa.PublicKey, error) {
+	pubKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		privKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Expected RSA key. Got %T", key)
+		}
+		pubKey = &privKey.PublicKey
+	}
+
+	// a degenerate exponent such as 1 makes signatures trivial to forge
+	if pubKey.E < 3 || pubKey.E%2 == 0 {
+		return nil, fmt.Errorf("Invalid RSA public exponent %d", pubKey.E)
+	}
+	if opts.MaxRSABits > 0 && pubKey.N.BitLen() > opts.MaxRSABits {
+		return nil, fmt.Errorf("RSA key of %d bits exceeds the %d bit limit", pubKey.N.BitLen(), opts.MaxRSABits)
+	}
+	return pubKey, nil
+}
+
+// Accept an ECDSA public or private key, or the equivalent crypto/ecdh
+// key on a NIST curve
+func ecdsaVerificationKey(key crypto.PublicKey) (*ecdsa.PublicKey, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdh.PrivateKey:
+		return ecdsaFromECDH(k.PublicKey())
+	case *ecdh.PublicKey:
+		return ecdsaFromECDH(k)
+	}
+	return nil, fmt.Errorf("Expected ECDSA key. Got %T", key)
+}
+
+// Convert through PKIX, which encodes both representations identically
+func ecdsaFromECDH(key *ecdh.PublicKey) (*ecdsa.PublicKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot convert ECDH key: %v", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot convert ECDH key: %v", err)
+	}
+	pubKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ECDH key on %v has no ECDSA equivalent", key.Curve())
+	}
+	return pubKey, nil
+}
+
+// Cheap rejection of RSA signatures that cannot possibly verify: a valid
+// signature is exactly as long as the modulus and is never zero. Only
+// the public key and the signature itself decide the outcome
+func validRSASignatureShape(pubKey *rsa.PublicKey, signature []byte) bool {
+	if len(signature) != pubKey.Size() {
+		return false
+	}
+	for _, b := range signature {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Prepare a signature check for alg, rejecting keys of the wrong type
+func newSignatureVerifier(alg Algorithm, key crypto.PublicKey, opts *VerifyOptions) (*signatureVerifier, error) {
+	switch alg {
+	case ALG_NONE:
+		// only allow plaintext if the caller explicitly passed in the
+		// "none" public key
+		if key != NoneKey {
+			return nil, errors.New("Refusing to validate plaintext JWS")
+		}
+
+		return &signatureVerifier{
+			verify: func(signature []byte) error {
+				return nil
+			},
+		}, nil
+
+	case ALG_HS256, ALG_HS384, ALG_HS512:
+		symmetricKey, ok := key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("Expected symmetric ([]byte) key. Got %T", key)
+		}
+
+		var hfunc func() hash.Hash
+		if alg == ALG_HS256 {
+			hfunc = sha256.New
+		} else if alg == ALG_HS384 {
+			hfunc = sha512.New384
+		} else if alg == ALG_HS512 {
+			hfunc = sha512.New
+		} else {
+			panic("Algorithm logic error with " + alg)
+		}
+
+		hm := hmac.New(hfunc, symmetricKey)
+		return &signatureVerifier{
+			input: hm,
+			verify: func(signature []byte) error {
+				// The MAC is always computed in full and compared in
+				// constant time. hmac.Equal returns early on a length
+				// mismatch, which only reveals that the length differs
+				// from the digest size of alg, a public value. Both
+				// failures return the same error
+				expectedSignature := hm.Sum(nil)
+				if !hmac.Equal(expectedSignature, signature) {
+					return ErrVerificationFailed
+				}
+				return nil
+			},
+		}, nil
+
+	case ALG_RS256, ALG_RS384, ALG_RS512:
+		pubKey, err := rsaVerificationKey(key, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var htype crypto.Hash
+		if alg == ALG_RS256 {
+			htype = crypto.SHA256
+		} else if alg == ALG_RS384 {
+			htype = crypto.SHA384
+		} else if alg == ALG_RS512 {
+			htype = crypto.SHA512
+		} else {
+			panic("Algorithm logic error with " + alg)
+		}
+
+		return digestVerifier(htype, func(digest, signature []byte) error {
+			if !validRSASignatureShape(pubKey, signature) {
+				return ErrVerificationFailed
+			}
+			if rsa.VerifyPKCS1v15(pubKey, htype, digest, signature) != nil {
+				return ErrVerificationFailed
+			}
+			return nil
+		}), nil
+
+	case ALG_ES256, ALG_ES384, ALG_ES512:
+		pubKey, err := ecdsaVerificationKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		var htype crypto.Hash
+		if alg == ALG_ES256 {
+			htype = crypto.SHA256
+		} else if alg == ALG_ES384 {
+			htype = crypto.SHA384
+		} else if alg == ALG_ES512 {
+			htype = crypto.SHA512
+		} else {
+			panic("Alorithm logic error with " + alg)
+		}
+
+		if curve := ecdsaCurves[alg]; pubKey.Curve != curve {
+			return nil, fmt.Errorf("%w: %s requires %s", ErrCurveMismatch, alg, curve.Params().Name)
+		}
+
+		// R and S are each as wide as the curve order: 32, 48 and 66
+		// bytes for P-256, P-384 and P-521
+		size := (pubKey.Curve.Params().BitSize + 7) / 8
+
+		return digestVerifier(htype, func(digest, signature []byte) error {
+			// split signature into R and S. A wrong length depends
+			// only on the signature, so rejecting it early reveals
+			// nothing
+			if len(signature) != 2*size {
+				return ErrVerificationFailed
+			}
+
+			r, s := new(big.Int), new(big.Int)
+			r.SetBytes(signature[:size])
+			s.SetBytes(signature[size:])
+
+			if opts.RequireLowS {
+				halfOrder := new(big.Int).Rsh(pubKey.Curve.Params().N, 1)
+				if s.Cmp(halfOrder) > 0 {
+					return ErrVerificationFailed
+				}
+			}
+
+			if !ecdsa.Verify(pubKey, digest, r, s) {
+				return ErrVerificationFailed
+			}
+			return nil
+		}), nil
+
+	case ALG_PS256, ALG_PS384, ALG_PS512:
+		pubKey, err := rsaVerificationKey(key, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var htype crypto.Hash
+		if alg == ALG_PS256 {
+			htype = crypto.SHA256
+		} else if alg == ALG_PS384 {
+			htype = crypto.SHA384
+		} else if alg == ALG_PS512 {
+			htype = crypto.SHA512
+		} else {
+			panic("Algorithm logic error with " + alg)
+		}
+
+		return digestVerifier(htype, func(digest, signature []byte) error {
+			if !validRSASignatureShape(pubKey, signature) {
+				return ErrVerificationFailed
+			}
+			// accept any salt length; RFC 7518 signers use the digest
+			// size but the salt is recovered during verification
+			if rsa.VerifyPSS(pubKey, htype, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) != nil {
+				return ErrVerificationFailed
+			}
+			return nil
+		}), nil
+
+	case ALG_EDDSA:
+		var pubKey ed25519.PublicKey
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			pubKey = k
+		case ed25519.PrivateKey:
+			if len(k) != ed25519.PrivateKeySize {
+				return nil, fmt.Errorf("Malformed Ed25519 private key of %d bytes", len(k))
+			}
+			pubKey = k.Public().(ed25519.PublicKey)
+		default:
+			return nil, fmt.Errorf("Expected Ed25519 key. Got %T", key)
+		}
+		if len(pubKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Malformed Ed25519 public key of %d bytes", len(pubKey))
+		}
+
+		// Ed25519 signs the message itself rather than a digest
+		var message bytes.Buffer
+		return &signatureVerifier{
+			input: &message,
+			verify: func(signature []byte) error {
+				if !ed25519.Verify(pubKey, message.Bytes(), signature) {
+					return ErrVerificationFailed
+				}
+				return nil
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("Unknown signature algorithm: %s", alg)
+	}
+}